@@ -0,0 +1,322 @@
+package main
+
+// Pos — позиция в исходном тексте (строка и столбец считаются с единицы)
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// Node — любой узел синтаксического дерева
+type Node interface {
+	Position() Pos
+}
+
+// Stmt — оператор программы
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Expr — выражение
+type Expr interface {
+	Node
+	exprNode()
+}
+
+type stmtBase struct{ pos Pos }
+
+func (s *stmtBase) Position() Pos { return s.pos }
+func (*stmtBase) stmtNode()       {}
+
+type exprBase struct{ pos Pos }
+
+func (e *exprBase) Position() Pos { return e.pos }
+func (*exprBase) exprNode()       {}
+
+// Program — корень дерева: операторы файла .clash в порядке следования
+type Program struct {
+	Stmts    []Stmt
+	Warnings []*ClashError // нераспознанные строки, пропущенные при разборе
+}
+
+// --- Выражения ---
+
+// NumberLit — числовой литерал (Int, BigInt, Float или Decimal)
+type NumberLit struct {
+	exprBase
+	Value Value
+}
+
+// BoolLit — логический литерал true / false
+type BoolLit struct {
+	exprBase
+	Value bool
+}
+
+// NilLit — литерал nil
+type NilLit struct {
+	exprBase
+}
+
+// StringLit — текст, взятый из программы как есть
+type StringLit struct {
+	exprBase
+	Value string
+}
+
+// TemplateLit — строка-шаблон print_formatted с полями {выражение:формат}
+type TemplateLit struct {
+	exprBase
+	Parts []TemplatePart
+}
+
+// TemplatePart — часть шаблона: текст или поле с выражением
+type TemplatePart struct {
+	Text string
+	Expr Expr   // nil для текста
+	Spec string // спецификатор формата после ":"
+}
+
+// Ident — имя переменной
+type Ident struct {
+	exprBase
+	Name string
+}
+
+// BareExpr — аргумент, который можно писать без кавычек: выражение, а если
+// оно не разбирается или ссылается на неопределённое имя — исходный текст
+type BareExpr struct {
+	exprBase
+	Expr  Expr     // nil, если текст не разбирается как выражение
+	Names []string // имена переменных, от которых зависит Expr
+	Raw   string
+}
+
+// ExistsExpr — exists(x): определено ли имя; само имя не вычисляется
+type ExistsExpr struct {
+	exprBase
+	Name string
+}
+
+// ListLit — литерал списка [1, 2, "a"]
+type ListLit struct {
+	exprBase
+	Items []Expr
+}
+
+// DictLit — литерал словаря {"ключ": значение}; пары в порядке записи
+type DictLit struct {
+	exprBase
+	Keys   []Expr
+	Values []Expr
+}
+
+// IndexExpr — обращение по индексу или ключу: xs[0], d["k"]
+type IndexExpr struct {
+	exprBase
+	Target Expr
+	Index  Expr
+}
+
+// UnaryExpr — унарная операция (минус, not)
+type UnaryExpr struct {
+	exprBase
+	Op      string
+	Operand Expr
+}
+
+// BinaryExpr — бинарная операция
+type BinaryExpr struct {
+	exprBase
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// CallExpr — вызов функции внутри выражения: sqrt(x), pow(a, b)
+type CallExpr struct {
+	exprBase
+	Name string
+	Args []Expr
+}
+
+// --- Операторы ---
+
+// InputMode определяет, как интерпретируется введённая строка
+type InputMode int
+
+const (
+	InputNumber InputMode = iota // solve.input
+	InputText                    // text.input
+	InputValue                   // input
+)
+
+// PrintStmt — print / print_formatted
+type PrintStmt struct {
+	stmtBase
+	Value     Expr
+	Formatted bool
+}
+
+// AssignStmt — имя = выражение или имя[индекс] = выражение
+type AssignStmt struct {
+	stmtBase
+	Target Expr // *Ident или *IndexExpr
+	Value  Expr
+}
+
+// InputStmt — solve.input, text.input, input
+type InputStmt struct {
+	stmtBase
+	Name string
+	Mode InputMode
+}
+
+// SolveStmt — solve (выражение)
+type SolveStmt struct {
+	stmtBase
+	Expr Expr
+}
+
+// TextStmt — text (a + b)
+type TextStmt struct {
+	stmtBase
+	Parts []Expr
+}
+
+// ResultOutStmt — solve.out / text.out: сохраняет последний результат в переменную
+type ResultOutStmt struct {
+	stmtBase
+	Name string
+}
+
+// IfStmt — if условие { ... } [else { ... }]
+type IfStmt struct {
+	stmtBase
+	Cond Expr
+	Body []Stmt
+	Else []Stmt // для else if — единственный вложенный IfStmt
+}
+
+// ElseStmt — } else {
+type ElseStmt struct {
+	stmtBase
+}
+
+// ElseIfStmt — } else if условие {
+type ElseIfStmt struct {
+	stmtBase
+	Cond Expr
+}
+
+// JumpStmt — jump / function_call: вызов функции, возможно с аргументами
+type JumpStmt struct {
+	stmtBase
+	Name string
+	Args []Expr
+}
+
+// MemoryOutStmt — memory out
+type MemoryOutStmt struct {
+	stmtBase
+}
+
+// MemoryLoadStmt — Memory load (f, g)
+type MemoryLoadStmt struct {
+	stmtBase
+	Names []string
+}
+
+// BuiltinStmt — вызов встроенной команды; аргументы идут в порядке параметров шаблона
+type BuiltinStmt struct {
+	stmtBase
+	Cmd  Command
+	Args []Expr
+}
+
+// ForStmt — for i from a to b { ... }
+type ForStmt struct {
+	stmtBase
+	Var   string
+	Start Expr
+	End   Expr
+	Body  []Stmt
+}
+
+// WhileStmt — while условие { ... }
+type WhileStmt struct {
+	stmtBase
+	Cond Expr
+	Body []Stmt
+}
+
+// DoStmt — do { ... } с завершающим условием while
+type DoStmt struct {
+	stmtBase
+	Body  []Stmt
+	While *DoWhileStmt
+}
+
+// DoWhileStmt — while условие (завершение do)
+type DoWhileStmt struct {
+	stmtBase
+	Cond Expr
+}
+
+// SwitchStmt — switch переменная { case ...: ... default: ... }
+type SwitchStmt struct {
+	stmtBase
+	Var     string
+	Cases   []*CaseStmt
+	Default *DefaultStmt
+}
+
+// CaseStmt — case значение: и операторы до следующей метки
+type CaseStmt struct {
+	stmtBase
+	Value Expr
+	Body  []Stmt
+}
+
+// DefaultStmt — default: и операторы до следующей метки
+type DefaultStmt struct {
+	stmtBase
+	Body []Stmt
+}
+
+// DefStmt — def (имя)
+type DefStmt struct {
+	stmtBase
+	Name string
+}
+
+// DefFuncStmt — def имя(a, b) { ... }
+type DefFuncStmt struct {
+	stmtBase
+	Name   string
+	Params []string
+	Body   []Stmt
+}
+
+// ReturnStmt — return [значение]
+type ReturnStmt struct {
+	stmtBase
+	Value Expr // nil для return без значения
+}
+
+// BreakStmt — break: выход из ближайшего цикла
+type BreakStmt struct {
+	stmtBase
+}
+
+// ContinueStmt — continue: переход к следующей итерации ближайшего цикла
+type ContinueStmt struct {
+	stmtBase
+}
+
+// FunctionDecl — Function (имя) ... )
+type FunctionDecl struct {
+	stmtBase
+	Name string
+	Body []Stmt
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"math"
+	"math/big"
+)
+
+// BigInt — целое число вне диапазона int64. Арифметика над Int переходит
+// на BigInt при переполнении, а результат, который снова помещается в int64,
+// возвращается как Int — так у одного числа всегда одно представление.
+type BigInt struct {
+	n *big.Int
+}
+
+func (BigInt) Type() Type { return TypeBigInt }
+
+func (b BigInt) String() string { return b.n.String() }
+
+// normalizeBig возвращает Int, если число помещается в int64, иначе BigInt
+func normalizeBig(n *big.Int) Value {
+	if n.IsInt64() {
+		return Int(n.Int64())
+	}
+	return BigInt{n}
+}
+
+// toBig приводит целое число (Int или BigInt) к *big.Int
+func toBig(v Value) (*big.Int, bool) {
+	switch n := v.(type) {
+	case Int:
+		return big.NewInt(int64(n)), true
+	case BigInt:
+		return n.n, true
+	}
+	return nil, false
+}
+
+// isInteger сообщает, что значение — целое число любого размера
+func isInteger(v Value) bool {
+	switch v.(type) {
+	case Int, BigInt:
+		return true
+	}
+	return false
+}
+
+// parseInteger разбирает десятичную запись целого числа любой длины
+func parseInteger(text string) (Value, bool) {
+	n, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, false
+	}
+	return normalizeBig(n), true
+}
+
+// intBinary выполняет арифметику над целыми: сначала в int64, а при
+// переполнении — точно, через math/big
+func intBinary(op string, left, right Value) (Value, error) {
+	if (op == "/" || op == "%" || op == "div") && right == Int(0) {
+		return nil, newError(KindZeroDivision, "деление на ноль")
+	}
+	l, leftIsInt := left.(Int)
+	r, rightIsInt := right.(Int)
+	if leftIsInt && rightIsInt {
+		if result, ok := int64Binary(op, l, r); ok {
+			return result, nil
+		}
+	}
+
+	a, _ := toBig(left)
+	b, _ := toBig(right)
+	result := new(big.Int)
+	switch op {
+	case "+":
+		result.Add(a, b)
+	case "-":
+		result.Sub(a, b)
+	case "*":
+		result.Mul(a, b)
+	case "/":
+		result.Quo(a, b)
+	case "%":
+		result.Rem(a, b)
+	case "div":
+		// Частное с округлением вниз: поправляем усечённое, если знаки разные
+		rem := new(big.Int)
+		result.QuoRem(a, b, rem)
+		if rem.Sign() != 0 && (rem.Sign() < 0) != (b.Sign() < 0) {
+			result.Sub(result, big.NewInt(1))
+		}
+	default:
+		return nil, newError(KindInternal, "неизвестный оператор %s", op)
+	}
+	return normalizeBig(result), nil
+}
+
+// int64Binary — быстрый путь для Int; ok == false означает переполнение
+func int64Binary(op string, left, right Int) (Value, bool) {
+	switch op {
+	case "+":
+		sum := left + right
+		if (left > 0 && right > 0 && sum < 0) || (left < 0 && right < 0 && sum >= 0) {
+			return nil, false
+		}
+		return sum, true
+	case "-":
+		diff := left - right
+		if (left >= 0 && right < 0 && diff < 0) || (left < 0 && right > 0 && diff >= 0) {
+			return nil, false
+		}
+		return diff, true
+	case "*":
+		if left == 0 || right == 0 {
+			return Int(0), true
+		}
+		product := left * right
+		if product/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+			return nil, false
+		}
+		return product, true
+	case "/", "div":
+		if left == math.MinInt64 && right == -1 {
+			return nil, false
+		}
+		if op == "/" {
+			return left / right, true
+		}
+		return floorDiv(left, right), true
+	case "%":
+		return left % right, true
+	}
+	return nil, false
+}
+
+// negateInt меняет знак целого; -MinInt64 уже не помещается в int64
+func negateInt(v Value) Value {
+	if n, ok := v.(Int); ok && n != math.MinInt64 {
+		return -n
+	}
+	b, _ := toBig(v)
+	return normalizeBig(new(big.Int).Neg(b))
+}
+
+// absInt — модуль целого числа
+func absInt(v Value) Value {
+	if b, ok := v.(BigInt); ok {
+		return normalizeBig(new(big.Int).Abs(b.n))
+	}
+	if v.(Int) < 0 {
+		return negateInt(v)
+	}
+	return v
+}
+
+// powInt возводит целое в неотрицательную целую степень точно
+func powInt(base, exp Value) (Value, error) {
+	b, _ := toBig(base)
+	e, _ := toBig(exp)
+	if !e.IsInt64() || (b.CmpAbs(big.NewInt(1)) > 0 && e.Int64() > maxPowExponent) {
+		return nil, newError(KindValue, "pow: слишком большая степень %s", e)
+	}
+	return normalizeBig(new(big.Int).Exp(b, e, nil)), nil
+}
+
+// maxPowExponent ограничивает степень в pow, чтобы случайный pow(2, 10^12)
+// не съел всю память
+const maxPowExponent = 1 << 20
+
+// compareInts сравнивает два целых любого размера
+func compareInts(left, right Value) int {
+	l, leftIsInt := left.(Int)
+	r, rightIsInt := right.(Int)
+	if leftIsInt && rightIsInt {
+		switch {
+		case l < r:
+			return -1
+		case l > r:
+			return 1
+		}
+		return 0
+	}
+	a, _ := toBig(left)
+	b, _ := toBig(right)
+	return a.Cmp(b)
+}
+
+// bigToFloat приводит BigInt к float64 (с потерей точности)
+func bigToFloat(b BigInt) float64 {
+	f, _ := new(big.Float).SetInt(b.n).Float64()
+	return f
+}
@@ -0,0 +1,50 @@
+package main
+
+// Сигналы управления передаются вверх как значения error: runBlock прерывает
+// блок и отдаёт сигнал тому, кто его обрабатывает (вызову функции или циклу).
+
+// returnSignal передаёт значение return вверх по вложенным блокам до вызова функции
+type returnSignal struct {
+	value Value
+}
+
+func (r *returnSignal) Error() string {
+	return "return вне функции"
+}
+
+// loopSignal — break или continue, передаётся вверх до ближайшего цикла
+type loopSignal struct {
+	keyword string
+}
+
+func (s *loopSignal) Error() string {
+	return s.keyword + " вне цикла"
+}
+
+var (
+	breakSignal    = &loopSignal{"break"}
+	continueSignal = &loopSignal{"continue"}
+)
+
+// isSignal сообщает, что ошибка — сигнал управления (return, break, continue),
+// а не ошибка выполнения
+func isSignal(err error) bool {
+	switch err.(type) {
+	case *returnSignal, *loopSignal:
+		return true
+	}
+	return false
+}
+
+// runLoopBody выполняет одну итерацию цикла. stop означает, что цикл нужно
+// завершить: по break (err == nil) или из-за return/ошибки (err != nil).
+func (i *Interpreter) runLoopBody(body []Stmt) (stop bool, err error) {
+	err = i.runBlock(body)
+	switch err {
+	case nil, continueSignal:
+		return false, nil
+	case breakSignal:
+		return true, nil
+	}
+	return true, err
+}
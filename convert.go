@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// convertInt — int(x): дробные числа усекаются к нулю, строка должна быть записью целого
+func convertInt(v Value) (Value, error) {
+	switch n := v.(type) {
+	case Int, BigInt:
+		return n, nil
+	case Bool:
+		if n {
+			return Int(1), nil
+		}
+		return Int(0), nil
+	case Float:
+		f := float64(n)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, newError(KindValue, "int: невозможно преобразовать %s в целое", n)
+		}
+		b, _ := big.NewFloat(math.Trunc(f)).Int(nil)
+		return normalizeBig(b), nil
+	case Decimal:
+		return normalizeBig(n.Round(0, RoundDown).unscaled), nil
+	case Str:
+		text := strings.TrimSpace(string(n))
+		if result, ok := parseInteger(text); ok {
+			return result, nil
+		}
+		return nil, newError(KindValue, "int: %q не является записью целого числа", string(n))
+	}
+	return nil, newError(KindType, "int: невозможно преобразовать значение типа %s", typeName(v))
+}
+
+// convertFloat — float(x)
+func convertFloat(v Value) (Value, error) {
+	switch n := v.(type) {
+	case Bool:
+		if n {
+			return Float(1), nil
+		}
+		return Float(0), nil
+	case Str:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(n)), 64)
+		if err != nil {
+			return nil, newError(KindValue, "float: %q не является записью числа", string(n))
+		}
+		return Float(f), nil
+	}
+	if f, ok := toFloat(v); ok {
+		return Float(f), nil
+	}
+	return nil, newError(KindType, "float: невозможно преобразовать значение типа %s", typeName(v))
+}
+
+// convertBool — bool(x): строка должна быть true/false или 1/0,
+// остальные значения приводятся так же, как в условии if
+func convertBool(v Value) (Value, error) {
+	s, ok := v.(Str)
+	if !ok {
+		return Bool(truthy(v)), nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(s))) {
+	case "true", "1":
+		return Bool(true), nil
+	case "false", "0":
+		return Bool(false), nil
+	}
+	return nil, newError(KindValue, "bool: %q не является логическим значением (true/false)", string(s))
+}
+
+// parseNumber разбирает введённое число: целое или дробное
+func parseNumber(text string) (Value, bool) {
+	text = strings.TrimSpace(text)
+	if n, ok := parseInteger(text); ok {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return Float(f), true
+	}
+	return nil, false
+}
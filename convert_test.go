@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestConversions(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"int(3.9)", TypeInt, "3"},
+		{"int(-3.9)", TypeInt, "-3"},
+		{`int(" 42 ")`, TypeInt, "42"},
+		{"int(true)", TypeInt, "1"},
+		{"int(2.75d)", TypeInt, "2"},
+		{`int("123456789012345678901234")`, TypeBigInt, "123456789012345678901234"},
+		{"int(pow(10.0, 20))", TypeBigInt, "100000000000000000000"},
+		{`float("2.5")`, TypeFloat, "2.5"},
+		{"float(3)", TypeFloat, "3"},
+		{"float(false)", TypeFloat, "0"},
+		{"str(1.5)", TypeString, "1.5"},
+		{"str([1, \"a\"])", TypeString, `[1, "a"]`},
+		{`bool("true")`, TypeBool, "true"},
+		{`bool("0")`, TypeBool, "false"},
+		{"bool(0)", TypeBool, "false"},
+		{"bool([1])", TypeBool, "true"},
+		{"type_of(1)", TypeString, "int"},
+		{"type_of(pow(2, 80))", TypeString, "bigint"},
+		{"type_of(1.0)", TypeString, "float"},
+		{`type_of("")`, TypeString, "string"},
+		{"type_of(nil)", TypeString, "nil"},
+		{"type_of({})", TypeString, "dict"},
+		{"type_of([])", TypeString, "list"},
+	})
+	checkExprErrors(t, []errorCase{
+		{`int("abc")`, KindValue},
+		{`int("1.5")`, KindValue},
+		{"int(nil)", KindType},
+		{`float("x")`, KindValue},
+		{`bool("maybe")`, KindValue},
+	})
+}
+
+// withStdin подставляет text как ввод пользователя на время теста
+func withStdin(t *testing.T, text string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+func TestNumberInput(t *testing.T) {
+	withStdin(t, "12.5\n")
+	out := mustRun(t, "solve.input() = n\nprint (type_of(n))")
+	if want := "Введите число для n: float\n"; out != want {
+		t.Errorf("вывод = %q, ожидалось %q", out, want)
+	}
+
+	withStdin(t, "двенадцать\n")
+	_, err := runProgram(t, "solve.input() = n")
+	if err == nil {
+		t.Fatal("ввод не числа должен быть ошибкой")
+	}
+	if kind := errorKind(t, err); kind != KindValue {
+		t.Errorf("вид ошибки %v, ожидалось неверное значение", kind)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Decimal — точное десятичное число: unscaled / 10^scale. Сложение, вычитание
+// и умножение точны; деление округляется по настройкам DecimalContext.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int // число знаков после запятой, не меньше нуля
+}
+
+func (Decimal) Type() Type { return TypeDecimal }
+
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// RoundingMode — способ округления decimal
+type RoundingMode int
+
+const (
+	RoundHalfEven RoundingMode = iota // к ближайшему, половина — к чётному
+	RoundHalfUp                       // к ближайшему, половина — от нуля
+	RoundHalfDown                     // к ближайшему, половина — к нулю
+	RoundDown                         // к нулю (отбрасывание)
+	RoundUp                           // от нуля
+	RoundFloor                        // вниз
+	RoundCeiling                      // вверх
+)
+
+var roundingModes = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"down":      RoundDown,
+	"up":        RoundUp,
+	"floor":     RoundFloor,
+	"ceiling":   RoundCeiling,
+}
+
+// DefaultDecimalPrecision — знаков после запятой в частном decimal по умолчанию
+const DefaultDecimalPrecision = 20
+
+// DecimalContext — настройки деления decimal: decimal.precision и decimal.rounding
+type DecimalContext struct {
+	Precision int
+	Rounding  RoundingMode
+}
+
+// parseDecimal разбирает запись вида -12.345
+func parseDecimal(text string) (Decimal, bool) {
+	intPart, frac, _ := strings.Cut(text, ".")
+	sign := ""
+	if strings.HasPrefix(intPart, "-") || strings.HasPrefix(intPart, "+") {
+		sign, intPart = intPart[:1], intPart[1:]
+	}
+	if intPart == "" && frac == "" {
+		return Decimal{}, false
+	}
+	for _, r := range intPart + frac {
+		if !isDigit(r) {
+			return Decimal{}, false
+		}
+	}
+	n, _ := new(big.Int).SetString(sign+"0"+intPart+frac, 10)
+	return Decimal{unscaled: n, scale: len(frac)}, true
+}
+
+// toDecimal приводит к decimal значение, которое переводится точно: целое или decimal
+func toDecimal(v Value) (Decimal, bool) {
+	switch n := v.(type) {
+	case Decimal:
+		return n, true
+	case Int, BigInt:
+		b, _ := toBig(n)
+		return Decimal{unscaled: b, scale: 0}, true
+	}
+	return Decimal{}, false
+}
+
+// makeDecimal — конструктор decimal(): из числа или строки
+func makeDecimal(v Value) (Value, error) {
+	if d, ok := toDecimal(v); ok {
+		return d, nil
+	}
+	text := v.String()
+	if f, ok := v.(Float); ok {
+		// Кратчайшая запись float: decimal(0.1) — это 0.1, а не 0.1000000000000000055...
+		text = strconv.FormatFloat(float64(f), 'f', -1, 64)
+	} else if _, ok := v.(Str); !ok {
+		return nil, newError(KindType, "decimal: ожидается число или строка, получено значение типа %s", typeName(v))
+	}
+	d, ok := parseDecimal(strings.TrimSpace(text))
+	if !ok {
+		return nil, newError(KindValue, "decimal: неверная запись числа %q", text)
+	}
+	return d, nil
+}
+
+// isDecimalOperand сообщает, что операция с этими значениями выполняется в decimal
+func isDecimalOperand(left, right Value) bool {
+	_, leftDec := left.(Decimal)
+	_, rightDec := right.(Decimal)
+	return leftDec || rightDec
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// aligned приводит два decimal к общему числу знаков после запятой
+func aligned(a, b Decimal) (x, y *big.Int, scale int) {
+	switch {
+	case a.scale > b.scale:
+		return a.unscaled, new(big.Int).Mul(b.unscaled, pow10(a.scale-b.scale)), a.scale
+	case a.scale < b.scale:
+		return new(big.Int).Mul(a.unscaled, pow10(b.scale-a.scale)), b.unscaled, b.scale
+	}
+	return a.unscaled, b.unscaled, a.scale
+}
+
+// compareDecimals сравнивает два decimal
+func compareDecimals(a, b Decimal) int {
+	x, y, _ := aligned(a, b)
+	return x.Cmp(y)
+}
+
+// decimalBinary выполняет арифметику decimal; целые операнды переводятся в decimal,
+// float — ошибка, чтобы погрешность двоичной дроби не попала в точный расчёт
+func decimalBinary(op string, left, right Value, ctx DecimalContext) (Value, error) {
+	a, leftOk := toDecimal(left)
+	b, rightOk := toDecimal(right)
+	if !leftOk || !rightOk {
+		if isFloatOperand(left, right) {
+			return nil, newError(KindType, "операция %s: decimal нельзя смешивать с float, используйте decimal()", op)
+		}
+		return nil, newError(KindType, "операция %s неприменима к типам %s и %s", op, typeName(left), typeName(right))
+	}
+	if (op == "/" || op == "%" || op == "div") && b.unscaled.Sign() == 0 {
+		return nil, newError(KindZeroDivision, "деление на ноль")
+	}
+
+	switch op {
+	case "+", "-":
+		x, y, scale := aligned(a, b)
+		result := new(big.Int)
+		if op == "+" {
+			result.Add(x, y)
+		} else {
+			result.Sub(x, y)
+		}
+		return Decimal{result, scale}, nil
+	case "*":
+		return Decimal{new(big.Int).Mul(a.unscaled, b.unscaled), a.scale + b.scale}, nil
+	case "/":
+		// a/b = ua·10^(p+sb) / (ub·10^sa) единиц 10^-p
+		num := new(big.Int).Mul(a.unscaled, pow10(ctx.Precision+b.scale))
+		den := new(big.Int).Mul(b.unscaled, pow10(a.scale))
+		return Decimal{roundQuo(num, den, ctx.Rounding), ctx.Precision}.trimZeros(), nil
+	case "div":
+		x, y, _ := aligned(a, b)
+		return Decimal{roundQuo(x, y, RoundFloor), 0}, nil
+	case "%":
+		// Остаток согласован с % для целых: знак как у делимого
+		x, y, scale := aligned(a, b)
+		quo := roundQuo(x, y, RoundDown)
+		return Decimal{new(big.Int).Sub(x, quo.Mul(quo, y)), scale}, nil
+	}
+	return nil, newError(KindInternal, "неизвестный оператор %s", op)
+}
+
+// roundQuo делит n на d и округляет частное до целого по mode
+func roundQuo(n, d *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	negative := (n.Sign() < 0) != (d.Sign() < 0)
+	// Сравниваем остаток с половиной делителя: 2|r| против |d|
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(new(big.Int).Abs(d))
+
+	var away bool
+	switch mode {
+	case RoundDown:
+	case RoundUp:
+		away = true
+	case RoundFloor:
+		away = negative
+	case RoundCeiling:
+		away = !negative
+	case RoundHalfUp:
+		away = cmp >= 0
+	case RoundHalfDown:
+		away = cmp > 0
+	default:
+		away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	}
+	if !away {
+		return q
+	}
+	if negative {
+		return q.Sub(q, big.NewInt(1))
+	}
+	return q.Add(q, big.NewInt(1))
+}
+
+// Round округляет до places знаков после запятой; places < 0 — до десятков, сотен...
+func (d Decimal) Round(places int, mode RoundingMode) Decimal {
+	if places >= d.scale {
+		return d
+	}
+	q := roundQuo(d.unscaled, pow10(d.scale-places), mode)
+	if places < 0 {
+		return Decimal{q.Mul(q, pow10(-places)), 0}
+	}
+	return Decimal{q, places}
+}
+
+// trimZeros убирает незначащие нули после запятой
+func (d Decimal) trimZeros() Decimal {
+	n := new(big.Int).Set(d.unscaled)
+	scale := d.scale
+	ten := big.NewInt(10)
+	r := new(big.Int)
+	for scale > 0 {
+		q, _ := new(big.Int).QuoRem(n, ten, r)
+		if r.Sign() != 0 {
+			break
+		}
+		n, scale = q, scale-1
+	}
+	return Decimal{n, scale}
+}
+
+// decimalToFloat приводит decimal к float64 (с потерей точности)
+func decimalToFloat(d Decimal) float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// roundValue — round (x, places): decimal округляется по режиму из контекста,
+// float — до ближайшего, целые при places >= 0 не меняются
+func roundValue(x Value, places int, ctx DecimalContext) (Value, error) {
+	switch n := x.(type) {
+	case Decimal:
+		return n.Round(places, ctx.Rounding), nil
+	case Int, BigInt:
+		if places >= 0 {
+			return n, nil
+		}
+		d, _ := toDecimal(n)
+		return normalizeBig(d.Round(places, ctx.Rounding).unscaled), nil
+	case Float:
+		if places < 0 {
+			return nil, newError(KindValue, "round: для float число знаков должно быть неотрицательным")
+		}
+		// Через десятичную запись, чтобы round(2.675, 2) совпадал с тем, что видно при выводе
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(n), 'f', places, 64), 64)
+		return Float(f), nil
+	}
+	return nil, newError(KindType, "round: ожидается число, получено значение типа %s", typeName(x))
+}
+
+// parseRoundingMode разбирает имя режима округления для decimal.rounding
+func parseRoundingMode(name string) (RoundingMode, error) {
+	mode, ok := roundingModes[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(roundingModes))
+		for n := range roundingModes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return 0, newError(KindValue, "неизвестный режим округления %q; допустимы: %s", name, strings.Join(names, ", "))
+	}
+	return mode, nil
+}
+
+// formatDecimal выводит decimal ровно с places знаками после запятой
+func formatDecimal(d Decimal, places int, mode RoundingMode) string {
+	d = d.Round(places, mode)
+	if d.scale < places {
+		d = Decimal{new(big.Int).Mul(d.unscaled, pow10(places-d.scale)), places}
+	}
+	return d.String()
+}
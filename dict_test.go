@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestDictLibrary(t *testing.T) {
+	const d = `{"b": 1, "a": 2, "c": 3}`
+	checkExprs(t, []exprCase{
+		{"dict_keys(" + d + ")", TypeList, `["b", "a", "c"]`},
+		{"dict_values(" + d + ")", TypeList, "[1, 2, 3]"},
+		{"dict_items(" + d + ")", TypeList, `[["b", 1], ["a", 2], ["c", 3]]`},
+		{"dict_has_key(" + d + `, "a")`, TypeBool, "true"},
+		{"dict_has_key(" + d + `, "z")`, TypeBool, "false"},
+		{"dict_get(" + d + `, "z", 0)`, TypeInt, "0"},
+		{"dict_get(" + d + `, "a", 0)`, TypeInt, "2"},
+		{`dict_merge({"a": 1, "b": 2}, {"b": 20, "c": nil})`, TypeDict, `{"a": 1, "b": 20, "c": nil}`},
+		{"len(" + d + ")", TypeInt, "3"},
+	})
+	checkExprErrors(t, []errorCase{
+		{`dict_get({}, "z")`, KindKey},
+		{`dict_delete({}, "z")`, KindKey},
+		{`dict_keys([1])`, KindType},
+		{`dict_get({}, 1, 0)`, KindType},
+	})
+}
+
+func TestDictMutation(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"d = {\"a\": 1, \"b\": 2, \"c\": 3}\ndict_delete (d, \"b\")\nd[\"b\"] = 4\nprint (dict_keys(d))", "[\"a\", \"c\", \"b\"]\n"},
+		// merge не меняет исходные словари
+		{"a = {\"x\": 1}\nm = dict_merge(a, {\"x\": 2})\nprint (a)\nprint (m)", "{\"x\": 1}\n{\"x\": 2}\n"},
+		// Значение по умолчанию может быть любого типа
+		{"d = {}\nx = dict_get(d, \"k\", \"нет\")\nprint (x)", "нет\n"},
+	})
+}
+
+func TestDictOrderIsStable(t *testing.T) {
+	d := NewDict()
+	for _, key := range []string{"z", "a", "m", "b"} {
+		d.Set(key, Int(len(key)))
+	}
+	d.Set("a", Int(0))
+	d.Delete("m")
+	want := `{"z": 1, "a": 0, "b": 1}`
+	if got := d.String(); got != want {
+		t.Errorf("%q, ожидалось %q", got, want)
+	}
+	if d.Delete("m") {
+		t.Error("повторное удаление должно вернуть false")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dispatchEntry — команда вместе со скомпилированным шаблоном
+type dispatchEntry struct {
+	Cmd   Command
+	Elems []patternElem
+}
+
+// literalPrefix — число литеральных лексем до первого параметра
+func (e dispatchEntry) literalPrefix() int {
+	for idx, el := range e.Elems {
+		if el.Param != "" {
+			return idx
+		}
+	}
+	return len(e.Elems)
+}
+
+// literalCount — общее число литеральных лексем шаблона
+func (e dispatchEntry) literalCount() int {
+	n := 0
+	for _, el := range e.Elems {
+		if el.Param == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// arity — число параметров шаблона
+func (e dispatchEntry) arity() int {
+	return len(e.Elems) - e.literalCount()
+}
+
+// sameSpecificity сообщает, что порядок двух команд в таблице определяется
+// только их id
+func (e dispatchEntry) sameSpecificity(other dispatchEntry) bool {
+	return e.literalPrefix() == other.literalPrefix() && e.literalCount() == other.literalCount()
+}
+
+// patternStates — множество позиций в шаблоне: бит 2*i — перед элементом i,
+// бит 2*i+1 — параметр i уже захватил хотя бы одну лексему
+type patternStates uint64
+
+// maxPatternElems — сколько элементов шаблона помещается в patternStates
+const maxPatternElems = 31
+
+// step — позиции шаблона после лексемы tok
+func step(elems []patternElem, states patternStates, tok Token) patternStates {
+	var next patternStates
+	for idx, el := range elems {
+		before := states&(1<<(2*idx)) != 0
+		inside := states&(1<<(2*idx+1)) != 0
+		switch {
+		case el.Param != "" && (before || inside):
+			next |= 1 << (2*idx + 1)
+		case el.Param == "" && before && sameToken(el.Literal, tok):
+			next |= 1 << (2 * (idx + 1))
+		}
+	}
+	// Параметр, захвативший лексему, может на этом закончиться
+	for idx, el := range elems {
+		if el.Param != "" && next&(1<<(2*idx+1)) != 0 {
+			next |= 1 << (2 * (idx + 1))
+		}
+	}
+	return next
+}
+
+// overlaps сообщает, что есть строка, подходящая под оба шаблона. Шаблоны
+// проходятся одновременно по литералам обоих шаблонов и одной лексеме, которой
+// нет ни в одном из них. Баланс скобок в параметрах не учитывается, так что
+// проверка строже, чем само сопоставление.
+func overlaps(a, b []patternElem) bool {
+	alphabet := []Token{{Kind: TokPunct, Text: "\x00"}}
+	for _, el := range append(append([]patternElem(nil), a...), b...) {
+		if el.Param == "" {
+			alphabet = append(alphabet, el.Literal)
+		}
+	}
+	endA, endB := patternStates(1)<<(2*len(a)), patternStates(1)<<(2*len(b))
+	queue := [][2]patternStates{{1, 1}}
+	seen := map[[2]patternStates]bool{queue[0]: true}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur[0]&endA != 0 && cur[1]&endB != 0 {
+			return true
+		}
+		for _, tok := range alphabet {
+			next := [2]patternStates{step(a, cur[0], tok), step(b, cur[1], tok)}
+			if next[0] != 0 && next[1] != 0 && !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// DispatchTable — таблица команд, упорядоченная от более специфичных шаблонов
+// к менее специфичным. Строится один раз при загрузке commands.json.
+type DispatchTable struct {
+	entries []dispatchEntry
+	byName  map[string][]dispatchEntry // имя команды в нижнем регистре
+}
+
+// NewDispatchTable компилирует шаблоны и проверяет их на неоднозначность
+func NewDispatchTable(commands []Command) (*DispatchTable, error) {
+	table := &DispatchTable{byName: make(map[string][]dispatchEntry)}
+	byID := make(map[int]Command)
+	for _, cmd := range commands {
+		if prev, ok := byID[cmd.ID]; ok {
+			return nil, fmt.Errorf("повторный id %d у команд %q и %q", cmd.ID, prev.Name, cmd.Name)
+		}
+		byID[cmd.ID] = cmd
+		elems, err := compilePattern(cmd.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(elems) == 0 || elems[0].Param != "" {
+			return nil, fmt.Errorf("шаблон команды %q должен начинаться с литерала", cmd.Name)
+		}
+		if len(elems) > maxPatternElems {
+			return nil, fmt.Errorf("шаблон команды %q длиннее %d элементов", cmd.Name, maxPatternElems)
+		}
+		for idx := 1; idx < len(elems); idx++ {
+			if elems[idx].Param != "" && elems[idx-1].Param != "" {
+				return nil, fmt.Errorf("в шаблоне %q параметры {{%s}} и {{%s}} идут подряд: их граница неоднозначна",
+					cmd.Pattern, elems[idx-1].Param, elems[idx].Param)
+			}
+		}
+		entry := dispatchEntry{Cmd: cmd, Elems: elems}
+		// Команды одинаковой специфичности упорядочены только по id: если
+		// одна строка подходит под обе, выбор между ними произволен
+		for _, prev := range table.entries {
+			if prev.sameSpecificity(entry) && overlaps(prev.Elems, entry.Elems) {
+				return nil, fmt.Errorf("неоднозначные шаблоны: %q (id %d) и %q (id %d)",
+					prev.Cmd.Pattern, prev.Cmd.ID, cmd.Pattern, cmd.ID)
+			}
+		}
+		table.entries = append(table.entries, entry)
+		name := strings.ToLower(cmd.Name)
+		table.byName[name] = append(table.byName[name], entry)
+	}
+
+	sort.SliceStable(table.entries, func(a, b int) bool {
+		ea, eb := table.entries[a], table.entries[b]
+		if pa, pb := ea.literalPrefix(), eb.literalPrefix(); pa != pb {
+			return pa > pb
+		}
+		if ca, cb := ea.literalCount(), eb.literalCount(); ca != cb {
+			return ca > cb
+		}
+		return ea.Cmd.ID < eb.Cmd.ID
+	})
+	return table, nil
+}
+
+// Entries возвращает команды в порядке сопоставления
+func (t *DispatchTable) Entries() []dispatchEntry {
+	return t.entries
+}
+
+// Lookup находит команду по имени без учёта регистра. Если команд с таким
+// именем несколько (round (x) и round (x, n)), выбирается та, у которой
+// число параметров равно arity, иначе — первая.
+func (t *DispatchTable) Lookup(name string, arity int) (dispatchEntry, bool) {
+	entries := t.byName[strings.ToLower(name)]
+	if len(entries) == 0 {
+		return dispatchEntry{}, false
+	}
+	for _, entry := range entries {
+		if entry.arity() == arity {
+			return entry, true
+		}
+	}
+	return entries[0], true
+}
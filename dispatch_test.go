@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// loadTestTable строит таблицу команд из commands.json
+func loadTestTable(t *testing.T) *DispatchTable {
+	t.Helper()
+	return newTestInterpreter(t).dispatch
+}
+
+// firstMatch — имя команды, под которую строка подходит первой
+func firstMatch(t *testing.T, table *DispatchTable, src string) string {
+	t.Helper()
+	line := lineTokens(t, src)
+	for _, entry := range table.Entries() {
+		if _, ok := matchPattern(entry.Elems, line); ok {
+			return entry.Cmd.Name
+		}
+	}
+	return ""
+}
+
+func TestDispatchOrder(t *testing.T) {
+	table := loadTestTable(t)
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"text.input() = x", "text.input"},
+		{"text (x)", "text"},
+		{"solve.input() = x", "solve.input"},
+		{"while x == 1 {", "while"},
+		{"while x == 1", "while_do"},
+		{"} else if x {", "else_if"},
+		{"} else {", "else"},
+		{"return", "return_empty"},
+		{"return x", "return"},
+	}
+	// Порядок не должен зависеть от запуска: проверяем несколько раз
+	for run := 0; run < 5; run++ {
+		for _, tt := range tests {
+			if got := firstMatch(t, table, tt.line); got != tt.want {
+				t.Errorf("%q: первой подходит %q, ожидалось %q", tt.line, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestDispatchLookupByArity(t *testing.T) {
+	table := loadTestTable(t)
+	for arity, id := range map[int]int{1: 18, 2: 70} {
+		entry, ok := table.Lookup("ROUND", arity)
+		if !ok || entry.Cmd.ID != id {
+			t.Errorf("Lookup(round, %d) = id %d, ожидалось %d", arity, entry.Cmd.ID, id)
+		}
+	}
+	if _, ok := table.Lookup("nosuch", 0); ok {
+		t.Error("Lookup нашёл несуществующую команду")
+	}
+}
+
+func TestDispatchRejectsAmbiguousPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		err      string // "" — таблица строится
+	}{
+		{"одинаковые шаблоны", []string{"foo ({{a}})", "foo ({{b}})"}, "неоднозначные шаблоны"},
+		{"пересечение одинаковой специфичности", []string{"foo {{a}} x {{b}}", "foo {{a}} y {{b}}"}, "неоднозначные шаблоны"},
+		{"параметры подряд", []string{"foo {{a}} {{b}}", "foo {{a}}"}, "идут подряд"},
+		{"разные литералы", []string{"foo ({{a}})", "foo [{{a}}]"}, ""},
+		{"более специфичный шаблон", []string{"foo {{a}} {", "foo {{a}}"}, ""},
+		{"шаблон с параметра", []string{"{{a}} foo"}, "должен начинаться с литерала"},
+	}
+	for _, tt := range tests {
+		var commands []Command
+		for idx, pattern := range tt.patterns {
+			commands = append(commands, Command{ID: idx + 1, Name: "foo", Pattern: pattern})
+		}
+		_, err := NewDispatchTable(commands)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: неожиданная ошибка %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: ошибка %v, ожидалось %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestDispatchRejectsDuplicateID(t *testing.T) {
+	_, err := NewDispatchTable([]Command{
+		{ID: 1, Name: "a", Pattern: "a"},
+		{ID: 1, Name: "b", Pattern: "b"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "повторный id") {
+		t.Errorf("ошибка %v, ожидался повторный id", err)
+	}
+}
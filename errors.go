@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind — категория ошибки программы
+type ErrorKind int
+
+const (
+	KindRuntime ErrorKind = iota
+	KindSyntax
+	KindName
+	KindType
+	KindValue
+	KindZeroDivision
+	KindIndex
+	KindKey
+	KindIO
+	KindStackOverflow
+	KindUnknownCommand
+	KindInternal
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindSyntax:
+		return "синтаксическая ошибка"
+	case KindName:
+		return "неизвестное имя"
+	case KindType:
+		return "ошибка типа"
+	case KindValue:
+		return "неверное значение"
+	case KindZeroDivision:
+		return "деление на ноль"
+	case KindIndex:
+		return "ошибка индекса"
+	case KindKey:
+		return "ошибка ключа"
+	case KindIO:
+		return "ошибка ввода-вывода"
+	case KindStackOverflow:
+		return "переполнение стека"
+	case KindUnknownCommand:
+		return "неизвестная команда"
+	case KindInternal:
+		return "внутренняя ошибка"
+	}
+	return "ошибка выполнения"
+}
+
+// ClashError — ошибка программы на ClashLang с указанием места в исходном тексте
+type ClashError struct {
+	Kind    ErrorKind
+	Message string
+	File    string
+	Pos     Pos    // нулевая позиция — место ещё не известно
+	Stmt    string // строка исходного текста с оператором, вызвавшим ошибку
+}
+
+// newError создаёт ошибку без позиции; позицию добавит ближайший узел дерева
+func newError(kind ErrorKind, format string, args ...interface{}) *ClashError {
+	return &ClashError{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// syntaxError создаёт ошибку разбора в указанной позиции
+func syntaxError(pos Pos, format string, args ...interface{}) *ClashError {
+	err := newError(KindSyntax, format, args...)
+	err.Pos = pos
+	return err
+}
+
+func (e *ClashError) Error() string {
+	var where string
+	switch {
+	case e.File != "" && e.Pos.Line > 0:
+		where = fmt.Sprintf("%s:%d:%d: ", e.File, e.Pos.Line, e.Pos.Col)
+	case e.Pos.Line > 0:
+		where = fmt.Sprintf("строка %d, столбец %d: ", e.Pos.Line, e.Pos.Col)
+	}
+	return fmt.Sprintf("%s%s: %s", where, e.Kind, e.Message)
+}
+
+// Diagnostic возвращает сообщение об ошибке со строкой программы и указателем ^ на место ошибки
+func (e *ClashError) Diagnostic() string {
+	var b strings.Builder
+	b.WriteString(e.Error())
+	if e.Stmt == "" || e.Pos.Line == 0 {
+		return b.String()
+	}
+	gutter := fmt.Sprintf("%d", e.Pos.Line)
+	fmt.Fprintf(&b, "\n %s | %s\n %s | ", gutter, e.Stmt, strings.Repeat(" ", len(gutter)))
+	// Отступ повторяет табуляции строки, чтобы ^ встал под нужный символ
+	col := 1
+	for _, r := range e.Stmt {
+		if col >= e.Pos.Col {
+			break
+		}
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+		col++
+	}
+	b.WriteString("^")
+	return b.String()
+}
+
+// at привязывает ошибку к узлу дерева, если у неё ещё нет позиции.
+// Сигналы управления проходят без изменений.
+func at(node Node, err error) error {
+	if err == nil || isSignal(err) {
+		return err
+	}
+	ce, ok := err.(*ClashError)
+	if !ok {
+		ce = &ClashError{Kind: KindRuntime, Message: err.Error()}
+	}
+	if ce.Pos.Line == 0 {
+		ce.Pos = node.Position()
+	}
+	return ce
+}
+
+// withSource дополняет ошибку именем файла и текстом строки, в которой она возникла
+func withSource(err error, file, src string) error {
+	ce, ok := err.(*ClashError)
+	if !ok {
+		return err
+	}
+	ce.File = file
+	lines := strings.Split(src, "\n")
+	if ce.Pos.Line > 0 && ce.Pos.Line <= len(lines) {
+		ce.Stmt = strings.TrimRight(lines[ce.Pos.Line-1], "\r")
+	}
+	return ce
+}
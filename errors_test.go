@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		src  string
+		kind ErrorKind
+		pos  Pos
+	}{
+		{"x = 1\nsolve (x / 0)", KindZeroDivision, Pos{Line: 2, Col: 10}},
+		{"print (1)\n  print (1 + \"a\")", KindType, Pos{Line: 2, Col: 12}},
+		{"solve (1 +)", KindUnknownCommand, Pos{Line: 1, Col: 1}},
+		{"if true {\n  print (1)", KindSyntax, Pos{Line: 1, Col: 1}},
+		{"print (\"abc)", KindSyntax, Pos{Line: 1, Col: 8}},
+	}
+	for _, tt := range tests {
+		interp := newTestInterpreter(t)
+		interp.SetStrict(true)
+		_, err := runWith(t, interp, tt.src)
+		if err == nil {
+			t.Errorf("%q: ожидалась ошибка", tt.src)
+			continue
+		}
+		ce := err.(*ClashError)
+		if ce.Kind != tt.kind || ce.Pos != tt.pos || ce.File != "test.clash" {
+			t.Errorf("%q: %s:%+v %v, ожидалось test.clash:%+v %v", tt.src, ce.File, ce.Pos, ce.Kind, tt.pos, tt.kind)
+		}
+	}
+}
+
+func TestDiagnosticCaret(t *testing.T) {
+	_, err := runProgram(t, "x = 1\n\tsolve (x / 0)")
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	got := err.(*ClashError).Diagnostic()
+	want := strings.Join([]string{
+		"test.clash:2:11: деление на ноль: деление на ноль",
+		" 2 | \tsolve (x / 0)",
+		"   | \t         ^",
+	}, "\n")
+	if got != want {
+		t.Errorf("диагностика:\n%s\nожидалось:\n%s", got, want)
+	}
+}
+
+func TestErrorStopsExecution(t *testing.T) {
+	out, err := runProgram(t, "print (1)\nsolve (1 / 0)\nprint (2)")
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	if out != "1\n" {
+		t.Errorf("после ошибки выполнение продолжилось: %q", out)
+	}
+}
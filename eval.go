@@ -0,0 +1,413 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"strings"
+)
+
+// mathFunc — математическая функция, доступная внутри выражений
+type mathFunc struct {
+	arity int
+	fn    func(args []float64) float64
+}
+
+var mathFuncs = map[string]mathFunc{
+	"abs":    {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":   {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"pow":    {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"round":  {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"sin":    {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":    {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":    {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"log":    {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log10":  {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"random": {0, func(a []float64) float64 { return rand.Float64() }},
+}
+
+// eval вычисляет значение выражения
+func (i *Interpreter) eval(e Expr) (Value, error) {
+	if err := i.enter(); err != nil {
+		return nil, at(e, err)
+	}
+	defer i.leave()
+	switch n := e.(type) {
+	case *NumberLit:
+		return n.Value, nil
+	case *BoolLit:
+		return Bool(n.Value), nil
+	case *StringLit:
+		return Str(n.Value), nil
+	case *TemplateLit:
+		return i.evalTemplate(n)
+	case *Ident:
+		if val, ok := i.scope.Lookup(n.Name); ok {
+			return val, nil
+		}
+		if fn, ok := i.functions[n.Name]; ok {
+			return &FuncValue{Fn: fn}, nil
+		}
+		return nil, at(n, newError(KindName, "переменная %s не определена", n.Name))
+	case *NilLit:
+		return Nil, nil
+	case *BareExpr:
+		if n.Expr == nil {
+			return Str(n.Raw), nil
+		}
+		for _, name := range n.Names {
+			_, isVar := i.scope.Lookup(name)
+			_, isFunc := i.functions[name]
+			if !isVar && !isFunc {
+				return Str(n.Raw), nil
+			}
+		}
+		return i.eval(n.Expr)
+	case *ExistsExpr:
+		_, isVar := i.scope.Lookup(n.Name)
+		_, isFunc := i.functions[n.Name]
+		return Bool(isVar || isFunc), nil
+	case *UnaryExpr:
+		operand, err := i.eval(n.Operand)
+		if err != nil {
+			return nil, err
+		}
+		val, err := evalUnary(n.Op, operand)
+		return val, at(n, err)
+	case *BinaryExpr:
+		left, err := i.eval(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		// and/or вычисляют правый операнд только при необходимости
+		if n.Op == "and" && !truthy(left) {
+			return Bool(false), nil
+		}
+		if n.Op == "or" && truthy(left) {
+			return Bool(true), nil
+		}
+		right, err := i.eval(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		val, err := i.evalBinary(n.Op, left, right)
+		return val, at(n, err)
+	case *ListLit:
+		items, err := i.evalArgs(n.Items)
+		if err != nil {
+			return nil, err
+		}
+		return NewList(items...), nil
+	case *DictLit:
+		dict := NewDict()
+		for idx, keyExpr := range n.Keys {
+			key, err := i.eval(keyExpr)
+			if err != nil {
+				return nil, err
+			}
+			name, err := expectString(key, "ключ словаря")
+			if err != nil {
+				return nil, at(keyExpr, err)
+			}
+			val, err := i.eval(n.Values[idx])
+			if err != nil {
+				return nil, err
+			}
+			dict.Set(name, val)
+		}
+		return dict, nil
+	case *IndexExpr:
+		target, index, err := i.evalIndexParts(n)
+		if err != nil {
+			return nil, err
+		}
+		val, err := indexValue(target, index)
+		return val, at(n, err)
+	case *CallExpr:
+		args, err := i.evalArgs(n.Args)
+		if err != nil {
+			return nil, err
+		}
+		val, err := i.call(n.Name, args, n.Position())
+		return val, at(n, err)
+	}
+	return nil, newError(KindInternal, "неизвестное выражение %T", e)
+}
+
+// evalIndexParts вычисляет контейнер и индекс выражения xs[i]
+func (i *Interpreter) evalIndexParts(n *IndexExpr) (target, index Value, err error) {
+	if target, err = i.eval(n.Target); err != nil {
+		return nil, nil, err
+	}
+	if index, err = i.eval(n.Index); err != nil {
+		return nil, nil, err
+	}
+	return target, index, nil
+}
+
+// indexValue читает элемент списка по номеру, значение словаря по ключу
+// или символ строки по номеру
+func indexValue(target, index Value) (Value, error) {
+	switch t := target.(type) {
+	case *List:
+		idx, err := listIndex(len(t.Items), index)
+		if err != nil {
+			return nil, err
+		}
+		return t.Items[idx], nil
+	case *Dict:
+		key, err := expectString(index, "ключ словаря")
+		if err != nil {
+			return nil, err
+		}
+		val, ok := t.Get(key)
+		if !ok {
+			return nil, newError(KindKey, "ключ %q не найден", key)
+		}
+		return val, nil
+	case Str:
+		runes := []rune(string(t))
+		idx, err := listIndex(len(runes), index)
+		if err != nil {
+			return nil, err
+		}
+		return Str(runes[idx]), nil
+	}
+	return nil, newError(KindType, "значение типа %s не поддерживает обращение по индексу", typeName(target))
+}
+
+// listIndex проверяет номер элемента последовательности длины size
+func listIndex(size int, index Value) (int, error) {
+	idx, err := expectInt(index, "индекс")
+	if err != nil {
+		return 0, err
+	}
+	if idx < 0 || idx >= size {
+		return 0, newError(KindIndex, "индекс %d вне диапазона", idx)
+	}
+	return idx, nil
+}
+
+// assign записывает значение в переменную или в элемент списка или словаря
+func (i *Interpreter) assign(target Expr, val Value) error {
+	switch t := target.(type) {
+	case *Ident:
+		i.scope.Assign(t.Name, val)
+		return nil
+	case *IndexExpr:
+		container, index, err := i.evalIndexParts(t)
+		if err != nil {
+			return err
+		}
+		switch c := container.(type) {
+		case *List:
+			idx, err := listIndex(len(c.Items), index)
+			if err != nil {
+				return at(t, err)
+			}
+			c.Items[idx] = val
+		case *Dict:
+			key, err := expectString(index, "ключ словаря")
+			if err != nil {
+				return at(t, err)
+			}
+			c.Set(key, val)
+		default:
+			return at(t, newError(KindType, "значение типа %s не поддерживает присваивание по индексу", typeName(container)))
+		}
+		return nil
+	}
+	return at(target, newError(KindSyntax, "присваивать можно только переменной или элементу"))
+}
+
+// call вызывает функцию из выражения: пользовательскую, а если такой нет —
+// встроенную команду из commands.json или математическую функцию
+func (i *Interpreter) call(name string, args []Value, pos Pos) (Value, error) {
+	if fn, ok := i.callable(name); ok {
+		return i.callFunction(fn, args, pos)
+	}
+	entry, ok := i.dispatch.Lookup(name, len(args))
+	if !ok {
+		return callMath(name, args)
+	}
+	if len(args) != entry.arity() {
+		return nil, newError(KindValue, "функция %s ожидает аргументов: %d, передано: %d", name, entry.arity(), len(args))
+	}
+	val, err := i.callBuiltin(entry.Cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return Nil, nil
+	}
+	return val, nil
+}
+
+// callMath вызывает математическую функцию из выражения
+func callMath(name string, args []Value) (Value, error) {
+	lower := strings.ToLower(name)
+	f, ok := mathFuncs[lower]
+	if !ok {
+		return nil, newError(KindName, "неизвестная функция %s", name)
+	}
+	if len(args) != f.arity {
+		return nil, newError(KindValue, "функция %s ожидает аргументов: %d, передано: %d", name, f.arity, len(args))
+	}
+	// abs и pow от целых считаются точно и остаются целыми
+	switch {
+	case lower == "abs" && isInteger(args[0]):
+		return absInt(args[0]), nil
+	case lower == "pow" && isInteger(args[0]) && isInteger(args[1]) && compareInts(args[1], Int(0)) >= 0:
+		return powInt(args[0], args[1])
+	}
+	nums := make([]float64, len(args))
+	for idx, arg := range args {
+		num, ok := toFloat(arg)
+		if !ok {
+			return nil, newError(KindType, "функция %s ожидает число, получено значение типа %s", name, typeName(arg))
+		}
+		nums[idx] = num
+	}
+	return Float(f.fn(nums)), nil
+}
+
+func evalUnary(op string, operand Value) (Value, error) {
+	if op == "not" {
+		return Bool(!truthy(operand)), nil
+	}
+	switch n := operand.(type) {
+	case Int, BigInt:
+		return negateInt(n), nil
+	case Decimal:
+		return Decimal{new(big.Int).Neg(n.unscaled), n.scale}, nil
+	case Float:
+		return -n, nil
+	}
+	return nil, newError(KindType, "операция %s неприменима к типу %s", op, typeName(operand))
+}
+
+// evalBinary выполняет арифметическую операцию.
+// Правила приведения: int op int -> int (при переполнении — bigint),
+// decimal op int или decimal -> decimal, если хотя бы один операнд float -> float.
+// Строки складываются друг с другом через +. Операнды других типов — ошибка типа.
+func (i *Interpreter) evalBinary(op string, left, right Value) (Value, error) {
+	switch op {
+	case "and", "or":
+		return Bool(truthy(right)), nil
+	case "=", "==":
+		return Bool(valuesEqual(left, right)), nil
+	case "!=":
+		return Bool(!valuesEqual(left, right)), nil
+	case "<", ">", "<=", ">=":
+		return compareOrdered(op, left, right)
+	}
+
+	if leftStr, ok := left.(Str); ok && op == "+" {
+		if rightStr, ok := right.(Str); ok {
+			return leftStr + rightStr, nil
+		}
+	}
+	if leftList, ok := left.(*List); ok && op == "+" {
+		if rightList, ok := right.(*List); ok {
+			return concatLists(leftList, rightList), nil
+		}
+	}
+	if isInteger(left) && isInteger(right) {
+		return intBinary(op, left, right)
+	}
+	if isDecimalOperand(left, right) {
+		return decimalBinary(op, left, right, i.decimal)
+	}
+	leftFloat, leftOk := toFloat(left)
+	rightFloat, rightOk := toFloat(right)
+	if !leftOk || !rightOk {
+		return nil, newError(KindType, "операция %s неприменима к типам %s и %s", op, typeName(left), typeName(right))
+	}
+	return floatBinary(op, leftFloat, rightFloat)
+}
+
+func floatBinary(op string, left, right float64) (Value, error) {
+	switch op {
+	case "+":
+		return Float(left + right), nil
+	case "-":
+		return Float(left - right), nil
+	case "*":
+		return Float(left * right), nil
+	case "/", "%", "div":
+		if right == 0 {
+			return nil, newError(KindZeroDivision, "деление на ноль")
+		}
+		switch op {
+		case "/":
+			return Float(left / right), nil
+		case "%":
+			return Float(math.Mod(left, right)), nil
+		}
+		return Float(math.Floor(left / right)), nil
+	}
+	return nil, newError(KindInternal, "неизвестный оператор %s", op)
+}
+
+// compareOrdered сравнивает два числа или две строки
+func compareOrdered(op string, left, right Value) (Value, error) {
+	cmp, ok := compareValues(left, right)
+	if !ok {
+		return nil, newError(KindType, "операция %s неприменима к типам %s и %s", op, typeName(left), typeName(right))
+	}
+	switch op {
+	case "<":
+		return Bool(cmp < 0), nil
+	case ">":
+		return Bool(cmp > 0), nil
+	case "<=":
+		return Bool(cmp <= 0), nil
+	}
+	return Bool(cmp >= 0), nil
+}
+
+// compareValues упорядочивает два числа или две строки; ok == false —
+// значения несравнимы
+func compareValues(left, right Value) (cmp int, ok bool) {
+	leftStr, leftIsStr := left.(Str)
+	rightStr, rightIsStr := right.(Str)
+	leftNum, leftIsNum := toFloat(left)
+	rightNum, rightIsNum := toFloat(right)
+	switch {
+	case leftIsStr && rightIsStr:
+		cmp = strings.Compare(string(leftStr), string(rightStr))
+	case leftIsNum && rightIsNum:
+		switch {
+		case isInteger(left) && isInteger(right):
+			// Целые сравниваются точно, без перевода во float64
+			cmp = compareInts(left, right)
+		case isDecimalOperand(left, right) && !isFloatOperand(left, right):
+			a, _ := toDecimal(left)
+			b, _ := toDecimal(right)
+			cmp = compareDecimals(a, b)
+		case leftNum < rightNum:
+			cmp = -1
+		case leftNum > rightNum:
+			cmp = 1
+		}
+	default:
+		return 0, false
+	}
+	return cmp, true
+}
+
+// floorDiv — целочисленное деление с округлением вниз
+func floorDiv(a, b Int) Int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// isFloatOperand сообщает, что хотя бы один операнд — float
+func isFloatOperand(left, right Value) bool {
+	_, leftFloat := left.(Float)
+	_, rightFloat := right.(Float)
+	return leftFloat || rightFloat
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// evalExpr вычисляет выражение через solve и возвращает результат
+func evalExpr(t *testing.T, src string) (Value, error) {
+	t.Helper()
+	interp := newTestInterpreter(t)
+	prog, err := interp.Parse("solve (" + src + ")")
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Warnings) > 0 {
+		return nil, prog.Warnings[0]
+	}
+	for _, stmt := range prog.Stmts {
+		if err := interp.ExecuteStatement(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return interp.lastResult, nil
+}
+
+// exprCase — выражение и ожидаемый результат: тип и вид при выводе
+type exprCase struct {
+	src  string
+	typ  Type
+	text string
+}
+
+func checkExprs(t *testing.T, tests []exprCase) {
+	t.Helper()
+	for _, tt := range tests {
+		val, err := evalExpr(t, tt.src)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.src, err)
+			continue
+		}
+		if val.Type() != tt.typ || val.String() != tt.text {
+			t.Errorf("%s = %s %s, ожидалось %s %s", tt.src, val.Type(), val, tt.typ, tt.text)
+		}
+	}
+}
+
+// errorCase — выражение, которое должно завершиться ошибкой указанного вида
+type errorCase struct {
+	src  string
+	kind ErrorKind
+}
+
+func checkExprErrors(t *testing.T, tests []errorCase) {
+	t.Helper()
+	for _, tt := range tests {
+		_, err := evalExpr(t, tt.src)
+		if err == nil {
+			t.Errorf("%s: ожидалась ошибка", tt.src)
+			continue
+		}
+		if kind := errorKind(t, err); kind != tt.kind {
+			t.Errorf("%s: ошибка %v (%v), ожидался вид %v", tt.src, err, kind, tt.kind)
+		}
+	}
+}
+
+func TestExprPrecedence(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"1 + 2 * 3", TypeInt, "7"},
+		{"(1 + 2) * 3", TypeInt, "9"},
+		{"10 - 4 - 3", TypeInt, "3"},
+		{"-5", TypeInt, "-5"},
+		{"-2 * -3", TypeInt, "6"},
+		{"2 - -3", TypeInt, "5"},
+		{"17 % 5", TypeInt, "2"},
+		{"-7 div 2", TypeInt, "-4"},
+		{"7 div 2 * 2", TypeInt, "6"},
+		{"1 + 10 % 4 * 2", TypeInt, "5"},
+		{"sqrt(16) + pow(2, 3)", TypeFloat, "12"},
+		{"abs(-3) * 2", TypeInt, "6"},
+		{"pow(2, 1 + 2)", TypeInt, "8"},
+	})
+}
+
+func TestExprDivisionByZero(t *testing.T) {
+	checkExprErrors(t, []errorCase{
+		{"1 / 0", KindZeroDivision},
+		{"5 % 0", KindZeroDivision},
+		{"5 div 0", KindZeroDivision},
+	})
+}
+
+func TestNumericPromotion(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"1 + 1.5", TypeFloat, "2.5"},
+		{"1.5 + 1", TypeFloat, "2.5"},
+		{"7 / 2", TypeInt, "3"},
+		{"7 / 2.0", TypeFloat, "3.5"},
+		{"2 * 0.5", TypeFloat, "1"},
+		{"7.5 div 2", TypeFloat, "3"},
+		{"1 < 1.5", TypeBool, "true"},
+		{"2 == 2.0", TypeBool, "true"},
+	})
+}
+
+func TestNumericTypeErrors(t *testing.T) {
+	checkExprErrors(t, []errorCase{
+		{`1 + "a"`, KindType},
+		{`"a" * 2`, KindType},
+		{`-"a"`, KindType},
+		{`1 < "a"`, KindType},
+	})
+}
+
+func TestNoPanicReachesUser(t *testing.T) {
+	_, err := runProgram(t, "x = 1\nsolve (x + \"a\")")
+	if err == nil {
+		t.Fatal("ожидалась ошибка типа")
+	}
+	if kind := errorKind(t, err); kind != KindType {
+		t.Errorf("вид ошибки %v, ожидалась ошибка типа", kind)
+	}
+}
+
+func TestRandintRange(t *testing.T) {
+	for _, r := range [][2]int{{5, 5}, {-3, 3}, {-math.MaxInt64, math.MaxInt64}, {math.MinInt64, math.MaxInt64}, {math.MinInt64, 0}} {
+		for n := 0; n < 100; n++ {
+			if v := randomBetween(r[0], r[1]); v < r[0] || v > r[1] {
+				t.Fatalf("randomBetween(%d, %d) = %d", r[0], r[1], v)
+			}
+		}
+	}
+	checkExprs(t, []exprCase{
+		{"type_of(randint(-9223372036854775807, 9223372036854775807))", TypeString, "int"},
+	})
+	checkExprErrors(t, []errorCase{
+		{"randint(2, 1)", KindValue},
+	})
+}
+
+func TestBooleanConditions(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"1 < 2 and 2 < 3", TypeBool, "true"},
+		{"1 > 2 or not (3 == 4)", TypeBool, "true"},
+		{"not 1 == 1", TypeBool, "false"},
+		{"2 != 2.5", TypeBool, "true"},
+		{`"abc" < "abd"`, TypeBool, "true"},
+		{`"a" == "a" and 1 >= 1 and 1 <= 1`, TypeBool, "true"},
+	})
+}
+
+func TestBoolLiteralsAndPredicates(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"true", TypeBool, "true"},
+		{"FALSE", TypeBool, "false"},
+		{"true and false", TypeBool, "false"},
+		{"1 < 2 == true", TypeBool, "true"},
+		{`contains("abc", "b")`, TypeBool, "true"},
+		{`starts_with("abc", "ab")`, TypeBool, "true"},
+		{`ends_with("abc", "b")`, TypeBool, "false"},
+	})
+}
+
+func TestBigIntPromotion(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"9223372036854775807 + 1", TypeBigInt, "9223372036854775808"},
+		{"-9223372036854775807 - 2", TypeBigInt, "-9223372036854775809"},
+		{"9223372036854775807 + 1 - 1", TypeInt, "9223372036854775807"},
+		{"4294967296 * 4294967296", TypeBigInt, "18446744073709551616"},
+		{"pow(2, 100)", TypeBigInt, "1267650600228229401496703205376"},
+		{"pow(2, 100) / pow(2, 98)", TypeInt, "4"},
+		{"pow(2, 100) % 7", TypeInt, "2"},
+		{"-pow(2, 70) div 3", TypeBigInt, "-393530540239137101142"},
+		{"abs(-pow(10, 20))", TypeBigInt, "100000000000000000000"},
+		{"-(-9223372036854775807 - 1)", TypeBigInt, "9223372036854775808"},
+		{"pow(2, 64) > 9223372036854775807", TypeBool, "true"},
+		{"pow(2, 64) == 18446744073709551616", TypeBool, "true"},
+		{"pow(2, 64) + 0.5", TypeFloat, "1.8446744073709552e+19"},
+		{"99999999999999999999999", TypeBigInt, "99999999999999999999999"},
+	})
+	checkExprErrors(t, []errorCase{
+		{"pow(2, 100) / 0", KindZeroDivision},
+		{"pow(2, 10000000)", KindValue},
+	})
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"0.1d + 0.2d", TypeDecimal, "0.3"},
+		{"0.1d + 0.2d == 0.3d", TypeBool, "true"},
+		{"decimal(\"19.99\") * 3", TypeDecimal, "59.97"},
+		{"decimal(0.1) + 1", TypeDecimal, "1.1"},
+		{"1d / 3d", TypeDecimal, "0.33333333333333333333"},
+		{"10d / 4", TypeDecimal, "2.5"},
+		{"7.5d div 2", TypeDecimal, "3"},
+		{"-7.5d % 2", TypeDecimal, "-1.5"},
+		{"1.10d - 0.1d", TypeDecimal, "1.00"},
+		{"round(2.675d, 2)", TypeDecimal, "2.68"},
+		{"round(2.665d, 2)", TypeDecimal, "2.66"},
+		{"round(1234, -2)", TypeInt, "1200"},
+		{"round(2.5d)", TypeDecimal, "2"},
+		{"abs(-1.5d)", TypeDecimal, "1.5"},
+		{"1.5d > 1", TypeBool, "true"},
+		{"type_of(1d)", TypeString, "decimal"},
+	})
+	checkExprErrors(t, []errorCase{
+		{"0.1d + 0.2", KindType},
+		{"1d / 0", KindZeroDivision},
+		{`decimal("1.2.3")`, KindValue},
+		{"round(1.5, -1)", KindValue},
+	})
+}
+
+func TestDecimalRoundingModes(t *testing.T) {
+	tests := []struct {
+		mode   RoundingMode
+		values []string // округление 2.5, -2.5, 2.4, -2.6 до целого
+	}{
+		{RoundHalfEven, []string{"2", "-2", "2", "-3"}},
+		{RoundHalfUp, []string{"3", "-3", "2", "-3"}},
+		{RoundHalfDown, []string{"2", "-2", "2", "-3"}},
+		{RoundDown, []string{"2", "-2", "2", "-2"}},
+		{RoundUp, []string{"3", "-3", "3", "-3"}},
+		{RoundFloor, []string{"2", "-3", "2", "-3"}},
+		{RoundCeiling, []string{"3", "-2", "3", "-2"}},
+	}
+	for _, tt := range tests {
+		for idx, src := range []string{"2.5", "-2.5", "2.4", "-2.6"} {
+			d, _ := parseDecimal(src)
+			if got := d.Round(0, tt.mode).String(); got != tt.values[idx] {
+				t.Errorf("режим %d: %s -> %s, ожидалось %s", tt.mode, src, got, tt.values[idx])
+			}
+		}
+	}
+}
+
+func TestNilAndUndefined(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"nil", TypeNil, "nil"},
+		{"nil == nil", TypeBool, "true"},
+		{"is_nil(nil)", TypeBool, "true"},
+		{"is_nil(0)", TypeBool, "false"},
+		{"exists(nosuch)", TypeBool, "false"},
+	})
+	checkExprErrors(t, []errorCase{
+		{"nosuch + 1", KindName},
+		{"nil + 1", KindType},
+	})
+}
+
+func TestCollectionLiteralsAndIndexing(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{`[1, 2, "a"]`, TypeList, `[1, 2, "a"]`},
+		{"[]", TypeList, "[]"},
+		{"[1, 2,]", TypeList, "[1, 2]"},
+		{`{"k": [1, {"x": nil}]}`, TypeDict, `{"k": [1, {"x": nil}]}`},
+		{"{}", TypeDict, "{}"},
+		{"[10, 20, 30][1]", TypeInt, "20"},
+		{`{"a": [1, 2, 3]}["a"][2]`, TypeInt, "3"},
+		{`"привет"[1]`, TypeString, "р"},
+		{"[1, 2][1 + 0] * -[3][0]", TypeInt, "-6"},
+		{`{"b": 1, "a": 2}`, TypeDict, `{"b": 1, "a": 2}`},
+	})
+	checkExprErrors(t, []errorCase{
+		{"[1][1]", KindIndex},
+		{"[1][-1]", KindIndex},
+		{`{"a": 1}["b"]`, KindKey},
+		{`[1]["a"]`, KindType},
+		{"{1: 2}", KindType},
+		{"5[0]", KindType},
+	})
+}
+
+func TestStringBuiltinsCountRunes(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{`substr("привет", 1, 2)`, TypeString, "ри"},
+		{`find("привет", "и")`, TypeInt, "2"},
+		{`"привет"[find("привет", "и")]`, TypeString, "и"},
+		{`find("привет", "я")`, TypeInt, "-1"},
+		{`substr("abc", 1, 9223372036854775807)`, TypeString, "bc"},
+	})
+	checkExprErrors(t, []errorCase{
+		{`substr("при", 3, 1)`, KindIndex},
+		{`substr("при", 0, -1)`, KindIndex},
+	})
+}
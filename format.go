@@ -0,0 +1,356 @@
+package main
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTemplate разбирает строку-шаблон print_formatted: "Привет, {name}! {count:.2f}".
+// Выражения в фигурных скобках разбираются по исходному тексту строки, поэтому
+// позиции их лексем совпадают с позициями в программе. {{ и }} — сами скобки.
+func (p *Parser) parseTemplate(tok Token) (*TemplateLit, error) {
+	raw := tok.Text[1 : len(tok.Text)-1]
+	tmpl := &TemplateLit{exprBase: exprBase{tok.Pos}}
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			tmpl.Parts = append(tmpl.Parts, TemplatePart{Text: unescape(text.String())})
+			text.Reset()
+		}
+	}
+
+	for idx := 0; idx < len(raw); idx++ {
+		c := raw[idx]
+		switch {
+		case c == '\\' && idx+1 < len(raw):
+			text.WriteString(raw[idx : idx+2])
+			idx++
+		case (c == '{' || c == '}') && idx+1 < len(raw) && raw[idx+1] == c:
+			text.WriteByte(c)
+			idx++
+		case c == '}':
+			return nil, syntaxError(p.rawPos(tok, idx), "непарная } в шаблоне; для самой скобки пишите }}")
+		case c == '{':
+			end, colon := templateField(raw, idx+1)
+			if end < 0 {
+				return nil, syntaxError(p.rawPos(tok, idx), "незакрытая { в шаблоне")
+			}
+			exprEnd := end
+			if colon >= 0 {
+				exprEnd = colon
+			}
+			part, err := p.parseTemplateExpr(tok, idx+1, exprEnd)
+			if err != nil {
+				return nil, err
+			}
+			if colon >= 0 {
+				part.Spec = raw[colon+1 : end]
+				if _, err := parseFormatSpec(part.Spec); err != nil {
+					return nil, syntaxError(p.rawPos(tok, colon+1), "%s", err.(*ClashError).Message)
+				}
+			}
+			flushText()
+			tmpl.Parts = append(tmpl.Parts, part)
+			idx = end
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flushText()
+	return tmpl, nil
+}
+
+// templateField ищет конец поля шаблона, начинающегося с from: индекс закрывающей }
+// и индекс : перед спецификатором формата (-1, если его нет). Скобки и строки
+// внутри выражения пропускаются.
+func templateField(raw string, from int) (end, colon int) {
+	depth := 0
+	colon = -1
+	for idx := from; idx < len(raw); idx++ {
+		switch raw[idx] {
+		case '\\':
+			// \" внутри шаблона — кавычка вложенной строки
+			idx++
+		case '(', '[', '{':
+			depth++
+		case ')', ']':
+			depth--
+		case '}':
+			if depth == 0 {
+				return idx, colon
+			}
+			depth--
+		case ':':
+			if depth == 0 && colon < 0 {
+				colon = idx
+			}
+		}
+	}
+	return -1, -1
+}
+
+// parseTemplateExpr разбирает выражение поля шаблона raw[from:to]
+func (p *Parser) parseTemplateExpr(tok Token, from, to int) (TemplatePart, error) {
+	raw := tok.Text[1 : len(tok.Text)-1]
+	src := strings.TrimSpace(raw[from:to])
+	if src == "" {
+		return TemplatePart{}, syntaxError(p.rawPos(tok, from), "пустое поле {} в шаблоне")
+	}
+	from += strings.Index(raw[from:to], src)
+	tokens, err := Tokenize(unescapeQuotes(src))
+	if err != nil {
+		return TemplatePart{}, syntaxError(p.rawPos(tok, from), "в шаблоне: %s", err.(*ClashError).Message)
+	}
+	// Лексемы поля получают позиции внутри строки программы
+	start := p.rawPos(tok, from)
+	for idx := range tokens {
+		tokens[idx].Pos = Pos{Line: start.Line, Col: start.Col + tokens[idx].Pos.Col - 1}
+		tokens[idx].Offset += tok.Offset + 1 + from
+	}
+	expr, err := p.parseExprSpan(tokens[:len(tokens)-1])
+	if err != nil {
+		return TemplatePart{}, syntaxError(start, "в шаблоне: %v", err)
+	}
+	return TemplatePart{Expr: expr}, nil
+}
+
+// rawPos — позиция в программе байта idx содержимого строки tok
+func (p *Parser) rawPos(tok Token, idx int) Pos {
+	raw := tok.Text[1:]
+	return Pos{Line: tok.Pos.Line, Col: tok.Pos.Col + 1 + utf8.RuneCountInString(raw[:idx])}
+}
+
+// unescape раскрывает escape-последовательности текста, уже проверенного лексером
+func unescape(raw string) string {
+	tokens, err := Tokenize(`"` + raw + `"`)
+	if err != nil {
+		return raw
+	}
+	return tokens[0].Value
+}
+
+// unescapeQuotes превращает \" в " — так записываются строки внутри полей шаблона
+func unescapeQuotes(src string) string {
+	return strings.ReplaceAll(src, `\"`, `"`)
+}
+
+// formatSpec — разобранный спецификатор формата поля шаблона:
+// [[заполнитель]выравнивание][+][#][0][ширина][.точность][тип]
+type formatSpec struct {
+	fill      rune
+	align     byte // '<', '>', '^' или 0 — по умолчанию
+	sign      bool
+	alternate bool // # — префикс 0x, 0o, 0b
+	zero      bool
+	width     int
+	precision int // -1 — не задана
+	verb      byte
+}
+
+// maxFormatWidth ограничивает ширину и точность поля, чтобы опечатка
+// в спецификаторе не выделяла гигабайты под заполнение
+const maxFormatWidth = 1000
+
+func parseFormatSpec(spec string) (formatSpec, error) {
+	fs := formatSpec{fill: ' ', precision: -1}
+	bad := newError(KindValue, "неверный спецификатор формата %q", spec)
+	rest := spec
+	if r, size := utf8.DecodeRuneInString(rest); size > 0 && size < len(rest) && strings.IndexByte("<>^", rest[size]) >= 0 {
+		fs.fill, fs.align = r, rest[size]
+		rest = rest[size+1:]
+	} else if rest != "" && strings.IndexByte("<>^", rest[0]) >= 0 {
+		fs.align = rest[0]
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, "+") {
+		fs.sign = true
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, "#") {
+		fs.alternate = true
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, "0") {
+		fs.zero = true
+		rest = rest[1:]
+	}
+	digits := 0
+	for digits < len(rest) && isDigit(rune(rest[digits])) {
+		digits++
+	}
+	if digits > 0 {
+		width, err := strconv.Atoi(rest[:digits])
+		if err != nil || width > maxFormatWidth {
+			return fs, newError(KindValue, "ширина %s в спецификаторе %q больше допустимой %d", rest[:digits], spec, maxFormatWidth)
+		}
+		fs.width = width
+		rest = rest[digits:]
+	}
+	if strings.HasPrefix(rest, ".") {
+		digits = 1
+		for digits < len(rest) && isDigit(rune(rest[digits])) {
+			digits++
+		}
+		if digits == 1 {
+			return fs, bad
+		}
+		precision, err := strconv.Atoi(rest[1:digits])
+		if err != nil || precision > maxFormatWidth {
+			return fs, newError(KindValue, "точность %s в спецификаторе %q больше допустимой %d", rest[1:digits], spec, maxFormatWidth)
+		}
+		fs.precision = precision
+		rest = rest[digits:]
+	}
+	switch {
+	case rest == "":
+	case len(rest) == 1 && strings.IndexByte("sdfexXob%", rest[0]) >= 0:
+		fs.verb = rest[0]
+	default:
+		return fs, bad
+	}
+	return fs, nil
+}
+
+// formatValue выводит значение по спецификатору формата поля шаблона;
+// decimal округляется по режиму ctx, как в round
+func formatValue(v Value, spec string, ctx DecimalContext) (string, error) {
+	if spec == "" {
+		return v.String(), nil
+	}
+	fs, err := parseFormatSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	verb := fs.verb
+	if verb == 0 {
+		switch v.(type) {
+		case Float, Decimal:
+			if fs.precision >= 0 {
+				verb = 'f'
+			}
+		case Int, BigInt:
+			verb = 'd'
+		}
+	}
+
+	var sign, prefix, body string
+	numeric := true
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		n, ok := toBig(v)
+		if !ok {
+			return "", newError(KindType, "формат %q требует int, получено значение типа %s", spec, typeName(v))
+		}
+		if n.Sign() < 0 {
+			sign, n = "-", new(big.Int).Neg(n)
+		}
+		switch verb {
+		case 'd':
+			body = n.Text(10)
+		case 'x', 'X':
+			body, prefix = n.Text(16), "0x"
+		case 'o':
+			body, prefix = n.Text(8), "0o"
+		case 'b':
+			body, prefix = n.Text(2), "0b"
+		}
+		if verb == 'X' {
+			body, prefix = strings.ToUpper(body), "0X"
+		}
+		if !fs.alternate {
+			prefix = ""
+		}
+	case 'f', 'e', '%':
+		if d, ok := v.(Decimal); ok && verb == 'f' {
+			// decimal выводится точно, без перевода во float64. Округляется
+			// число со знаком: для floor и ceiling знак важен
+			precision := fs.precision
+			if precision < 0 {
+				precision = d.scale
+			}
+			d = d.Round(precision, ctx.Rounding)
+			if d.unscaled.Sign() < 0 {
+				sign, d = "-", Decimal{new(big.Int).Neg(d.unscaled), d.scale}
+			}
+			body = formatDecimal(d, precision, ctx.Rounding)
+			break
+		}
+		f, ok := toFloat(v)
+		if !ok {
+			return "", newError(KindType, "формат %q требует число, получено значение типа %s", spec, typeName(v))
+		}
+		if f < 0 {
+			sign, f = "-", -f
+		}
+		precision := fs.precision
+		if precision < 0 {
+			precision = 6
+		}
+		switch verb {
+		case 'f':
+			body = strconv.FormatFloat(f, 'f', precision, 64)
+		case 'e':
+			body = strconv.FormatFloat(f, 'e', precision, 64)
+		default:
+			body = strconv.FormatFloat(f*100, 'f', precision, 64) + "%"
+		}
+	default:
+		numeric = false
+		body = v.String()
+		if fs.precision >= 0 && utf8.RuneCountInString(body) > fs.precision {
+			body = string([]rune(body)[:fs.precision])
+		}
+	}
+	if numeric && sign == "" && fs.sign {
+		sign = "+"
+	}
+
+	head := sign + prefix
+	pad := fs.width - utf8.RuneCountInString(head+body)
+	if pad <= 0 {
+		return head + body, nil
+	}
+	if fs.zero && fs.align == 0 && numeric {
+		// Нули встают между знаком и цифрами: -0042
+		return head + strings.Repeat("0", pad) + body, nil
+	}
+	fill := string(fs.fill)
+	align := fs.align
+	if align == 0 {
+		align = '<'
+		if numeric {
+			align = '>'
+		}
+	}
+	switch align {
+	case '<':
+		return head + body + strings.Repeat(fill, pad), nil
+	case '>':
+		return strings.Repeat(fill, pad) + head + body, nil
+	}
+	left := pad / 2
+	return strings.Repeat(fill, left) + head + body + strings.Repeat(fill, pad-left), nil
+}
+
+// evalTemplate подставляет значения полей шаблона
+func (i *Interpreter) evalTemplate(t *TemplateLit) (Value, error) {
+	var b strings.Builder
+	for _, part := range t.Parts {
+		if part.Expr == nil {
+			b.WriteString(part.Text)
+			continue
+		}
+		val, err := i.eval(part.Expr)
+		if err != nil {
+			return nil, err
+		}
+		text, err := formatValue(val, part.Spec, i.decimal)
+		if err != nil {
+			return nil, at(part.Expr, err)
+		}
+		b.WriteString(text)
+	}
+	return Str(b.String()), nil
+}
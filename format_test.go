@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var defaultDecimal = DecimalContext{Precision: DefaultDecimalPrecision, Rounding: RoundHalfEven}
+
+func TestFormatValue(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tests := []struct {
+		val  Value
+		spec string
+		want string
+	}{
+		{Int(42), "", "42"},
+		{Int(42), "5", "   42"},
+		{Int(42), "<5", "42   "},
+		{Int(42), "^6", "  42  "},
+		{Int(42), "*>6", "****42"},
+		{Int(-42), "05", "-0042"},
+		{Int(42), "+d", "+42"},
+		{Int(255), "x", "ff"},
+		{Int(255), "#X", "0XFF"},
+		{Int(8), "#o", "0o10"},
+		{Int(5), "b", "101"},
+		{Float(3.14159), ".2f", "3.14"},
+		{Float(3.14159), "8.3f", "   3.142"},
+		{Int(3), ".1f", "3.0"},
+		{Float(1234.5), ".2e", "1.23e+03"},
+		{Float(0.256), ".1%", "25.6%"},
+		{Str("привет"), "8", "привет  "},
+		{Str("привет"), ".3", "при"},
+		{BigInt{huge}, "d", "123456789012345678901234567890"},
+		{Decimal{big.NewInt(12345), 3}, ".2f", "12.34"},
+		{Decimal{big.NewInt(1), 30}, "f", "0." + strings.Repeat("0", 29) + "1"},
+	}
+	for _, tt := range tests {
+		got, err := formatValue(tt.val, tt.spec, defaultDecimal)
+		if err != nil {
+			t.Errorf("formatValue(%v, %q): %v", tt.val, tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("formatValue(%v, %q) = %q, ожидалось %q", tt.val, tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSpecErrors(t *testing.T) {
+	for _, spec := range []string{"q", ".f", "5.2fx", "99999999999999999999", ".1001f", "1001"} {
+		if _, err := parseFormatSpec(spec); err == nil {
+			t.Errorf("parseFormatSpec(%q): ожидалась ошибка", spec)
+		}
+	}
+	if _, err := formatValue(Str("a"), "d", defaultDecimal); err == nil {
+		t.Error("формат d для строки должен быть ошибкой")
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"name = \"Аня\"\ncount = 2.5\nprint_formatted (\"Привет, {name}! {count:.2f} очка\")", "Привет, Аня! 2.50 очка\n"},
+		{"a = 2\nprint_formatted (\"{a} * 3 = {a * 3:>4}\")", "2 * 3 =    6\n"},
+		{"print_formatted (\"{{буквально}} {1 + 1}\")", "{буквально} 2\n"},
+		{"s = \"x\"\nprint_formatted (\"{upper(s)}|{len(\\\"ab\\\")}\")", "X|2\n"},
+	})
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		col int
+	}{
+		{`print_formatted ("a {b")`, 21},
+		{`print_formatted ("a } b")`, 21},
+		{`print_formatted ("{}")`, 20},
+		{`print_formatted ("{n:99999999999999999999}")`, 22},
+		{`print_formatted ("{n:.q}")`, 22},
+	}
+	for _, tt := range tests {
+		_, err := newTestInterpreter(t).Parse(tt.src)
+		if err == nil {
+			t.Errorf("%s: ожидалась ошибка разбора", tt.src)
+			continue
+		}
+		ce := err.(*ClashError)
+		if ce.Kind != KindSyntax || ce.Pos.Col != tt.col {
+			t.Errorf("%s: %v, ожидалась синтаксическая ошибка в столбце %d", tt.src, ce, tt.col)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxDepth — допустимая глубина вложенных вызовов по умолчанию
+const DefaultMaxDepth = 1000
+
+// MaxDepthLimit — наибольшая допустимая глубина вызовов. От переполнения стека Go
+// защищает MaxNesting: вызов с глубоко вложенными блоками занимает много уровней.
+const MaxDepthLimit = 10000
+
+// MaxNesting — наибольшая вложенность блоков и выражений вместе со всеми
+// вызовами. Уровень занимает в стеке Go до 2 КБ (больше всего — у jump), так
+// что предел держит стек далеко от 1 ГБ, при котором Go аварийно завершает программу.
+const MaxNesting = 100000
+
+// maxChainShown — сколько последних вызовов показывать в сообщении о переполнении стека
+const maxChainShown = 10
+
+// Function — пользовательская функция: def name(a, b) { ... } или Function (name)
+type Function struct {
+	Name   string
+	Params []string
+	Body   []Stmt
+	// Inline — процедура старого вида (Function (...) или def (...)): выполняется
+	// в области видимости вызывающего, как раньше, и работает с его переменными
+	Inline bool
+}
+
+// stackOverflow описывает переполнение стека вместе с цепочкой вызовов
+func stackOverflow(reason string, frames []*CallFrame) *ClashError {
+	chain := make([]string, 0, maxChainShown+1)
+	if len(frames) > maxChainShown {
+		chain = append(chain, fmt.Sprintf("... ещё вызовов: %d", len(frames)-maxChainShown))
+		frames = frames[len(frames)-maxChainShown:]
+	}
+	for _, f := range frames {
+		chain = append(chain, fmt.Sprintf("%s (строка %d)", f.Function.Name, f.Pos.Line))
+	}
+	return newError(KindStackOverflow, "%s\nцепочка вызовов: %s", reason, strings.Join(chain, " -> "))
+}
+
+// enter отмечает вход в блок или выражение; вложенность ограничена MaxNesting,
+// потому что глубина вызовов сама по себе не ограничивает рост стека Go
+func (i *Interpreter) enter() error {
+	if i.nesting >= MaxNesting {
+		return stackOverflow(fmt.Sprintf("вложенность блоков и выражений превысила %d", MaxNesting), i.frames)
+	}
+	i.nesting++
+	return nil
+}
+
+// leave отмечает выход из блока или выражения
+func (i *Interpreter) leave() {
+	i.nesting--
+}
+
+// callable находит функцию по имени: переменную со значением-функцией
+// или функцию, объявленную в программе
+func (i *Interpreter) callable(name string) (*Function, bool) {
+	if val, ok := i.scope.Lookup(name); ok {
+		if fn, ok := val.(*FuncValue); ok {
+			return fn.Fn, true
+		}
+	}
+	fn, ok := i.functions[name]
+	return fn, ok
+}
+
+// lookupFunction — callable с ошибкой для неизвестного имени
+func (i *Interpreter) lookupFunction(name string) (*Function, error) {
+	if fn, ok := i.callable(name); ok {
+		return fn, nil
+	}
+	return nil, newError(KindName, "функция %s не определена", name)
+}
+
+// callFunction выполняет пользовательскую функцию и возвращает значение из return
+// (nil, если функция завершилась без return). Каждый вызов получает свой кадр;
+// локальная область функции вложена в глобальную, а не в область вызывающего.
+func (i *Interpreter) callFunction(fn *Function, args []Value, pos Pos) (Value, error) {
+	if len(args) != len(fn.Params) {
+		return nil, newError(KindValue, "функция %s ожидает аргументов: %d, передано: %d", fn.Name, len(fn.Params), len(args))
+	}
+	if len(i.frames) >= i.maxDepth {
+		frames := append([]*CallFrame(nil), i.frames...)
+		frames = append(frames, &CallFrame{Function: fn, Pos: pos})
+		return nil, stackOverflow(fmt.Sprintf("глубина вызовов превысила %d", i.maxDepth), frames)
+	}
+
+	i.frames = append(i.frames, &CallFrame{Function: fn, Pos: pos})
+	defer func() { i.frames = i.frames[:len(i.frames)-1] }()
+
+	scope := i.scope
+	if !fn.Inline {
+		scope = NewFrameScope(i.globals)
+		for idx, param := range fn.Params {
+			scope.Define(param, args[idx])
+		}
+	}
+	err := i.withScope(scope, func() error {
+		return i.runBlock(fn.Body)
+	})
+	if ret, ok := err.(*returnSignal); ok {
+		return ret.value, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Nil, nil
+}
+
+// evalArgs вычисляет аргументы вызова слева направо
+func (i *Interpreter) evalArgs(exprs []Expr) ([]Value, error) {
+	args := make([]Value, len(exprs))
+	for idx, arg := range exprs {
+		val, err := i.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[idx] = val
+	}
+	return args, nil
+}
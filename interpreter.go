@@ -5,10 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"os"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -29,695 +28,770 @@ type CommandList struct {
 
 // Interpreter управляет выполнением программы
 type Interpreter struct {
-	commands   map[int]Command
-	variables  map[string]interface{}
-	lastResult interface{}
-	functions  map[string][]string
-	inIfBlock  bool
-	ifBlock    []string
+	dispatch   *DispatchTable
+	globals    *Scope
+	scope      *Scope // текущая область видимости
+	frames     []*CallFrame
+	maxDepth   int
+	nesting    int  // текущая вложенность блоков и выражений, см. enter
+	strict     bool // нераспознанная строка прерывает разбор программы
+	decimal    DecimalContext
+	lastResult Value
+	functions  map[string]*Function
 }
 
-func NewInterpreter() *Interpreter {
+func NewInterpreter() (*Interpreter, error) {
 	rand.Seed(time.Now().UnixNano()) // Инициализация генератора случайных чисел
+	globals := NewFrameScope(nil)
 	interp := &Interpreter{
-		commands:  make(map[int]Command),
-		variables: make(map[string]interface{}),
-		functions: make(map[string][]string),
-		ifBlock:   []string{},
+		globals:    globals,
+		lastResult: Nil,
+		scope:      globals,
+		functions:  make(map[string]*Function),
+		maxDepth:   DefaultMaxDepth,
+		decimal:    DecimalContext{Precision: DefaultDecimalPrecision, Rounding: RoundHalfEven},
 	}
-	interp.loadCommands()
-	return interp
+	if err := interp.loadCommands(); err != nil {
+		return nil, err
+	}
+	return interp, nil
 }
 
-func (i *Interpreter) loadCommands() {
+// loadCommands читает commands.json и строит таблицу разбора команд
+func (i *Interpreter) loadCommands() error {
 	file, err := os.ReadFile("commands.json")
 	if err != nil {
-		fmt.Println("Ошибка загрузки commands.json:", err)
-		return
+		return fmt.Errorf("ошибка загрузки commands.json: %v", err)
 	}
 	var cmdList CommandList
 	if err := json.Unmarshal(file, &cmdList); err != nil {
-		fmt.Println("Ошибка разбора JSON:", err)
-		return
+		return fmt.Errorf("ошибка разбора JSON: %v", err)
 	}
-	for _, cmd := range cmdList.Commands {
-		i.commands[cmd.ID] = cmd
+	table, err := NewDispatchTable(cmdList.Commands)
+	if err != nil {
+		return fmt.Errorf("ошибка в commands.json: %v", err)
 	}
+	i.dispatch = table
+	return nil
 }
 
-func (i *Interpreter) matchCommand(line string) (Command, map[string]string, bool) {
-	line = strings.TrimSpace(line)
-	lineLower := strings.ToLower(line)
-
-	for _, cmd := range i.commands {
-		pattern := strings.ToLower(cmd.Pattern)
-		parts := strings.Split(pattern, "{{")
-		if len(parts) == 1 && lineLower == pattern {
-			return cmd, nil, true
-		}
-		if strings.HasPrefix(lineLower, parts[0]) {
-			remainder := strings.TrimPrefix(line, cmd.Pattern[:len(parts[0])])
-			if len(parts) > 1 {
-				params := make(map[string]string)
-				for _, part := range parts[1:] {
-					end := strings.Index(part, "}}")
-					if end == -1 {
-						continue
-					}
-					varName := part[:end]
-					closing := part[end+2:]
-					if strings.HasSuffix(remainder, closing) {
-						value := strings.TrimSuffix(remainder, closing)
-						params[varName] = value
-						return cmd, params, true
-					} else {
-						nextPartIdx := strings.Index(remainder, " ")
-						if nextPartIdx != -1 {
-							params[varName] = remainder[:nextPartIdx]
-							remainder = strings.TrimSpace(remainder[nextPartIdx:])
-						}
-					}
-				}
-			}
-		}
+// SetMaxDepth задаёт допустимую глубину вложенных вызовов функций
+func (i *Interpreter) SetMaxDepth(depth int) error {
+	if depth < 1 {
+		return fmt.Errorf("глубина вызовов должна быть положительной, получено %d", depth)
 	}
-	return Command{}, nil, false
+	if depth > MaxDepthLimit {
+		return fmt.Errorf("глубина вызовов не может превышать %d, получено %d", MaxDepthLimit, depth)
+	}
+	i.maxDepth = depth
+	return nil
 }
 
-func (i *Interpreter) ExecuteStatement(line string) {
-	if idx := strings.Index(line, "//"); idx != -1 {
-		line = line[:idx]
-	}
-	line = strings.TrimSpace(line)
-	if line == "" {
-		return
+// SetStrict включает строгий режим: первая нераспознанная строка — ошибка,
+// иначе такие строки пропускаются с предупреждением
+func (i *Interpreter) SetStrict(strict bool) {
+	i.strict = strict
+}
+
+// Parse строит синтаксическое дерево программы по командам из commands.json
+func (i *Interpreter) Parse(program string) (*Program, error) {
+	parser, err := NewParser(program, i.dispatch)
+	if err != nil {
+		return nil, err
 	}
+	parser.strict = i.strict
+	return parser.ParseProgram()
+}
 
-	lineLower := strings.ToLower(line)
+// ExecuteStatement выполняет один оператор. Ошибка возвращается как *ClashError
+// с позицией в программе; сигналы управления (return, break, continue) — как есть.
+func (i *Interpreter) ExecuteStatement(stmt Stmt) error {
+	return at(stmt, i.execute(stmt))
+}
 
-	switch {
-	case strings.HasPrefix(lineLower, "memory load ("):
-		funcNames := strings.Trim(strings.TrimPrefix(line, "Memory load ("), ")")
-		for _, funcName := range strings.Split(funcNames, ",") {
-			funcName = strings.TrimSpace(funcName)
-			if cmds, ok := i.functions[funcName]; ok {
-				for _, cmd := range cmds {
-					i.ExecuteStatement(cmd)
-				}
+func (i *Interpreter) execute(stmt Stmt) error {
+	switch s := stmt.(type) {
+	case *FunctionDecl:
+		i.functions[s.Name] = &Function{Name: s.Name, Body: s.Body, Inline: true}
+	case *DefFuncStmt:
+		i.functions[s.Name] = &Function{Name: s.Name, Params: s.Params, Body: s.Body}
+	case *ReturnStmt:
+		ret := &returnSignal{value: Nil}
+		if s.Value != nil {
+			val, err := i.eval(s.Value)
+			if err != nil {
+				return err
 			}
+			ret.value = val
 		}
-	case lineLower == "}":
-		if i.inIfBlock {
-			i.inIfBlock = false
-			for _, cmd := range i.ifBlock {
-				i.ExecuteStatement(cmd)
+		return ret
+	case *BreakStmt:
+		return breakSignal
+	case *ContinueStmt:
+		return continueSignal
+	case *MemoryLoadStmt:
+		for _, funcName := range s.Names {
+			fn, err := i.lookupFunction(funcName)
+			if err != nil {
+				return err
 			}
-			i.ifBlock = []string{}
-		}
-	default:
-		cmd, params, matched := i.matchCommand(line)
-		if !matched {
-			if i.inIfBlock {
-				i.ifBlock = append(i.ifBlock, line)
+			if _, err := i.callFunction(fn, nil, s.Position()); err != nil {
+				return err
 			}
-			return
 		}
-		switch cmd.ID {
-		case 1: // Print
-			varName := params["var"]
-			if val, ok := i.variables[varName]; ok {
-				fmt.Println(val)
-			} else {
-				fmt.Println(varName)
-			}
-		case 2: // Solve.input
-			varName := params["var"]
-			fmt.Printf("Введите число для %s: ", varName)
-			reader := bufio.NewReader(os.Stdin)
-			input, _ := reader.ReadString('\n')
-			num, _ := strconv.Atoi(strings.TrimSpace(input))
-			i.variables[varName] = num
-		case 3: // Solve
-			expr := params["expr"]
-			i.lastResult = parseExpression(expr, i.variables)
-		case 4: // Solve.out
-			varName := params["var"]
-			i.variables[varName] = i.lastResult
-		case 5: // Text.input
-			varName := params["var"]
-			fmt.Printf("Введите текст для %s: ", varName)
-			reader := bufio.NewReader(os.Stdin)
-			input, _ := reader.ReadString('\n')
-			i.variables[varName] = strings.TrimSpace(input)
-		case 6: // Text
-			expr := params["expr"]
-			i.lastResult = parseTextExpression(expr, i.variables)
-		case 7: // Text.out
-			varName := params["var"]
-			i.variables[varName] = i.lastResult
-		case 8: // If
-			varName := params["var"]
-			valueStr := params["value"]
-			value, _ := strconv.Atoi(valueStr)
-			if val, ok := i.variables[varName].(int); ok && val == value {
-				i.inIfBlock = true
-			}
-		case 9: // jump
-			funcName := params["func"]
-			if cmds, ok := i.functions[funcName]; ok {
-				for _, cmd := range cmds {
-					i.ExecuteStatement(cmd)
-				}
-			}
-		case 10: // memory out
-			// Форматированный вывод переменных
-			var keys []string
-			for k := range i.variables {
-				keys = append(keys, k)
-			}
-			sort.Strings(keys) // Сортировка ключей по алфавиту
-			for idx, key := range keys {
-				if val, ok := i.variables[key]; ok {
-					fmt.Printf("%d) %v\n", idx+1, val)
-				}
-			}
-		case 13: // Text.length
-			varName := params["var"]
-			if val, ok := i.variables[varName].(string); ok {
-				i.lastResult = utf8.RuneCountInString(val)
-			} else {
-				fmt.Println("Ошибка: переменная не является текстом")
-			}
-		case 14: // Text.upper
-			varName := params["var"]
-			if val, ok := i.variables[varName].(string); ok {
-				i.lastResult = strings.ToUpper(val)
-			} else {
-				fmt.Println("Ошибка: переменная не является текстом")
-			}
-		// Новые функции
-		case 15: // abs
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Abs(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = float64(math.Abs(float64(val)))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 16: // sqrt
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Sqrt(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = math.Sqrt(float64(val))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 17: // pow
-			baseStr := params["base"]
-			expStr := params["exponent"]
-			var base, exp float64
-			if val, ok := i.variables[baseStr]; ok {
-				if v, ok := val.(int); ok {
-					base = float64(v)
-				} else if v, ok := val.(float64); ok {
-					base = v
-				}
-			} else {
-				base, _ = strconv.ParseFloat(baseStr, 64)
-			}
-			if val, ok := i.variables[expStr]; ok {
-				if v, ok := val.(int); ok {
-					exp = float64(v)
-				} else if v, ok := val.(float64); ok {
-					exp = v
-				}
-			} else {
-				exp, _ = strconv.ParseFloat(expStr, 64)
-			}
-			i.lastResult = math.Pow(base, exp)
-		case 18: // round
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Round(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = float64(val)
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 19: // sin
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Sin(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = math.Sin(float64(val))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 20: // cos
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Cos(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = math.Cos(float64(val))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 21: // tan
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Tan(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = math.Tan(float64(val))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 22: // log
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Log(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = math.Log(float64(val))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 23: // log10
-			varName := params["var"]
-			if val, ok := i.variables[varName].(float64); ok {
-				i.lastResult = math.Log10(val)
-			} else if val, ok := i.variables[varName].(int); ok {
-				i.lastResult = math.Log10(float64(val))
-			} else {
-				fmt.Println("Ошибка: переменная не является числом")
-			}
-		case 24: // random
-			i.lastResult = rand.Float64()
-		case 25: // randint
-			minStr := params["min"]
-			maxStr := params["max"]
-			var min, max int
-			if val, ok := i.variables[minStr]; ok {
-				if v, ok := val.(int); ok {
-					min = v
-				}
-			} else {
-				min, _ = strconv.Atoi(minStr)
-			}
-			if val, ok := i.variables[maxStr]; ok {
-				if v, ok := val.(int); ok {
-					max = v
-				}
-			} else {
-				max, _ = strconv.Atoi(maxStr)
-			}
-			i.lastResult = rand.Intn(max-min+1) + min
-		case 26: // len
-			varName := params["var"]
-			if val, ok := i.variables[varName].(string); ok {
-				i.lastResult = utf8.RuneCountInString(val)
-			} else {
-				fmt.Println("Ошибка: переменная не является строкой")
-			}
-		case 27: // substr
-			varName := params["var"]
-			startStr := params["start"]
-			lengthStr := params["length"]
-			start, _ := strconv.Atoi(startStr)
-			length, _ := strconv.Atoi(lengthStr)
-			if val, ok := i.variables[varName].(string); ok {
-				if start >= 0 && start < len(val) && length >= 0 {
-					if start+length > len(val) {
-						length = len(val) - start
-					}
-					i.lastResult = val[start : start+length]
-				} else {
-					fmt.Println("Ошибка: неверные индексы")
-				}
-			} else {
-				fmt.Println("Ошибка: переменная не является строкой")
-			}
-		case 28: // find
-			strVar := params["str"]
-			subVar := params["sub"]
-			if str, ok := i.variables[strVar].(string); ok {
-				if sub, ok := i.variables[subVar].(string); ok {
-					i.lastResult = strings.Index(str, sub)
-				} else {
-					fmt.Println("Ошибка: подстрока не является строкой")
-				}
-			} else {
-				fmt.Println("Ошибка: строка не является строкой")
-			}
-		case 29: // replace
-			strVar := params["str"]
-			oldVar := params["old"]
-			newVar := params["new"]
-			if str, ok := i.variables[strVar].(string); ok {
-				if old, ok := i.variables[oldVar].(string); ok {
-					if new, ok := i.variables[newVar].(string); ok {
-						i.lastResult = strings.Replace(str, old, new, -1)
-					} else {
-						fmt.Println("Ошибка: новое значение не является строкой")
-					}
-				} else {
-					fmt.Println("Ошибка: старое значение не является строкой")
-				}
-			} else {
-				fmt.Println("Ошибка: строка не является строкой")
-			}
-		case 30: // split
-			strVar := params["str"]
-			sepVar := params["sep"]
-			if str, ok := i.variables[strVar].(string); ok {
-				if sep, ok := i.variables[sepVar].(string); ok {
-					i.lastResult = strings.Split(str, sep)
-				} else {
-					fmt.Println("Ошибка: разделитель не является строкой")
-				}
-			} else {
-				fmt.Println("Ошибка: строка не является строкой")
+	case *PrintStmt:
+		val, err := i.eval(s.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Println(val)
+	case *InputStmt:
+		return i.execInput(s)
+	case *SolveStmt:
+		val, err := i.eval(s.Expr)
+		if err != nil {
+			return err
+		}
+		i.lastResult = val
+	case *AssignStmt:
+		val, err := i.eval(s.Value)
+		if err != nil {
+			return err
+		}
+		if err := i.assign(s.Target, val); err != nil {
+			return err
+		}
+	case *ResultOutStmt:
+		i.scope.Assign(s.Name, i.lastResult)
+	case *TextStmt:
+		var result string
+		for _, part := range s.Parts {
+			val, err := i.eval(part)
+			if err != nil {
+				return err
 			}
-		case 31: // join
-			sliceVar := params["slice"]
-			sepVar := params["sep"]
-			if slice, ok := i.variables[sliceVar].([]string); ok {
-				if sep, ok := i.variables[sepVar].(string); ok {
-					i.lastResult = strings.Join(slice, sep)
-				} else {
-					fmt.Println("Ошибка: разделитель не является строкой")
+			if val, ok := val.(Str); ok {
+				if result != "" {
+					result += " "
 				}
+				result += string(val)
 			} else {
-				fmt.Println("Ошибка: переменная не является срезом строк")
-			}
-		case 32: // lower
-			varName := params["var"]
-			if val, ok := i.variables[varName].(string); ok {
-				i.lastResult = strings.ToLower(val)
-			} else {
-				fmt.Println("Ошибка: переменная не является строкой")
-			}
-		case 33: // for
-			varName := params["var"]
-			startStr := params["start"]
-			endStr := params["end"]
-			start, _ := strconv.Atoi(startStr)
-			end, _ := strconv.Atoi(endStr)
-			for j := start; j <= end; j++ {
-				i.variables[varName] = j
-				for _, stmt := range i.ifBlock {
-					i.ExecuteStatement(stmt)
-				}
-			}
-			i.ifBlock = []string{}
-		case 34: // while
-			condVar := params["var"]
-			condValueStr := params["value"]
-			condValue, _ := strconv.Atoi(condValueStr)
-			for {
-				if val, ok := i.variables[condVar].(int); ok && val == condValue {
-					for _, stmt := range i.ifBlock {
-						i.ExecuteStatement(stmt)
-					}
-				} else {
-					break
-				}
+				return at(part, newError(KindType, "text ожидает текст, получено значение типа %s", typeName(val)))
 			}
-			i.ifBlock = []string{}
-		case 35: // do
-			i.inIfBlock = true
-		case 36: // while_do
-			condVar := params["var"]
-			condValueStr := params["value"]
-			condValue, _ := strconv.Atoi(condValueStr)
-			for {
-				for _, stmt := range i.ifBlock {
-					i.ExecuteStatement(stmt)
-				}
-				if val, ok := i.variables[condVar].(int); ok && val != condValue {
-					break
-				}
-			}
-			i.ifBlock = []string{}
-		case 37: // switch
-			varName := params["var"]
-			i.variables["switch_var"] = i.variables[varName]
-			i.inIfBlock = true
-		case 38: // case
-			if i.inIfBlock {
-				valueStr := params["value"]
-				value, _ := strconv.Atoi(valueStr)
-				if val, ok := i.variables["switch_var"].(int); ok && val == value {
-					for _, stmt := range i.ifBlock {
-						i.ExecuteStatement(stmt)
-					}
+		}
+		i.lastResult = Str(result)
+	case *IfStmt:
+		holds, err := i.condHolds(s.Cond)
+		if err != nil {
+			return err
+		}
+		if holds {
+			return i.runBlock(s.Body)
+		}
+		return i.runBlock(s.Else)
+	case *JumpStmt:
+		fn, err := i.lookupFunction(s.Name)
+		if err != nil {
+			return err
+		}
+		args, err := i.evalArgs(s.Args)
+		if err != nil {
+			return err
+		}
+		result, err := i.callFunction(fn, args, s.Position())
+		if err != nil {
+			return err
+		}
+		if result != Nil {
+			i.lastResult = result
+		}
+	case *MemoryOutStmt:
+		// Форматированный вывод видимых переменных, по алфавиту
+		vars := i.scope.Visible()
+		for idx, key := range sortedNames(vars) {
+			fmt.Printf("%d) %v\n", idx+1, vars[key])
+		}
+	case *ForStmt:
+		startVal, err := i.eval(s.Start)
+		if err != nil {
+			return err
+		}
+		endVal, err := i.eval(s.End)
+		if err != nil {
+			return err
+		}
+		start, err := expectInt(startVal, "начало цикла for")
+		if err != nil {
+			return at(s.Start, err)
+		}
+		end, err := expectInt(endVal, "конец цикла for")
+		if err != nil {
+			return at(s.End, err)
+		}
+		// Переменная цикла живёт в собственной области блока
+		loopScope := NewScope(i.scope)
+		return i.withScope(loopScope, func() error {
+			if start > end {
+				return nil
+			}
+			// Выход до увеличения счётчика: j++ после end = MaxInt64 переполнился бы
+			for j := start; ; j++ {
+				loopScope.Define(s.Var, Int(j))
+				if stop, err := i.runLoopBody(s.Body); stop {
+					return err
 				}
-			}
-			i.ifBlock = []string{}
-		case 39: // default
-			if i.inIfBlock {
-				for _, stmt := range i.ifBlock {
-					i.ExecuteStatement(stmt)
+				if j == end {
+					return nil
 				}
 			}
-			i.ifBlock = []string{}
-		case 40: // file.read
-			fileName := params["file"]
-			content, err := os.ReadFile(fileName)
+		})
+	case *WhileStmt:
+		for {
+			holds, err := i.condHolds(s.Cond)
 			if err != nil {
-				fmt.Println("Ошибка чтения файла:", err)
-			} else {
-				i.lastResult = string(content)
-			}
-		case 41: // file.write
-			fileName := params["file"]
-			if content, ok := i.lastResult.(string); ok {
-				err := os.WriteFile(fileName, []byte(content), 0644)
-				if err != nil {
-					fmt.Println("Ошибка записи файла:", err)
-				}
-			}
-		case 42: // print_formatted
-			varName := params["var"]
-			if val, ok := i.variables[varName]; ok {
-				fmt.Printf("%v\n", val)
-			} else {
-				fmt.Printf("%s\n", varName)
-			}
-		case 43: // input
-			varName := params["var"]
-			fmt.Printf("Введите значение для %s: ", varName)
-			reader := bufio.NewReader(os.Stdin)
-			input, _ := reader.ReadString('\n')
-			i.variables[varName] = strings.TrimSpace(input)
-		case 44: // array_create
-			sizeStr := params["size"]
-			size, _ := strconv.Atoi(sizeStr)
-			i.lastResult = make([]interface{}, size)
-		case 45: // array_set
-			arrayVar := params["array"]
-			indexStr := params["index"]
-			valueVar := params["value"]
-			index, _ := strconv.Atoi(indexStr)
-			if array, ok := i.variables[arrayVar].([]interface{}); ok {
-				if index >= 0 && index < len(array) {
-					if val, ok := i.variables[valueVar]; ok {
-						array[index] = val
-					}
-				}
-			}
-		case 46: // array_get
-			arrayVar := params["array"]
-			indexStr := params["index"]
-			index, _ := strconv.Atoi(indexStr)
-			if array, ok := i.variables[arrayVar].([]interface{}); ok {
-				if index >= 0 && index < len(array) {
-					i.lastResult = array[index]
-				} else {
-					fmt.Println("Ошибка: индекс вне диапазона")
-				}
+				return err
 			}
-		case 47: // list_create
-			i.lastResult = []interface{}{}
-		case 48: // list_append
-			listVar := params["list"]
-			valueVar := params["value"]
-			if list, ok := i.variables[listVar].([]interface{}); ok {
-				if val, ok := i.variables[valueVar]; ok {
-					i.variables[listVar] = append(list, val)
-				}
+			if !holds {
+				break
 			}
-		case 49: // list_get
-			listVar := params["list"]
-			indexStr := params["index"]
-			index, _ := strconv.Atoi(indexStr)
-			if list, ok := i.variables[listVar].([]interface{}); ok {
-				if index >= 0 && index < len(list) {
-					i.lastResult = list[index]
-				} else {
-					fmt.Println("Ошибка: индекс вне диапазона")
-				}
+			if stop, err := i.runLoopBody(s.Body); stop {
+				return err
 			}
-		case 50: // dict_create
-			i.lastResult = make(map[string]interface{})
-		case 51: // dict_set
-			dictVar := params["dict"]
-			keyVar := params["key"]
-			valueVar := params["value"]
-			if dict, ok := i.variables[dictVar].(map[string]interface{}); ok {
-				if key, ok := i.variables[keyVar].(string); ok {
-					if val, ok := i.variables[valueVar]; ok {
-						dict[key] = val
-					}
-				}
+		}
+	case *DoStmt:
+		for {
+			if stop, err := i.runLoopBody(s.Body); stop {
+				return err
 			}
-		case 52: // dict_get
-			dictVar := params["dict"]
-			keyVar := params["key"]
-			if dict, ok := i.variables[dictVar].(map[string]interface{}); ok {
-				if key, ok := i.variables[keyVar].(string); ok {
-					if val, exists := dict[key]; exists {
-						i.lastResult = val
-					} else {
-						fmt.Println("Ошибка: ключ не найден")
-					}
-				}
+			holds, err := i.condHolds(s.While.Cond)
+			if err != nil {
+				return err
 			}
-		case 53: // time
-			i.lastResult = time.Now().Format("15:04:05")
-		case 54: // date
-			i.lastResult = time.Now().Format("2006-01-02")
-		case 55: // env
-			varName := params["var"]
-			i.lastResult = os.Getenv(varName)
-		case 56: // def
-			funcName := params["name"]
-			i.functions[funcName] = []string{}
-		case 57: // function_call
-			funcName := params["func"]
-			if cmds, ok := i.functions[funcName]; ok {
-				for _, cmd := range cmds {
-					i.ExecuteStatement(cmd)
-				}
+			if !holds {
+				break
 			}
 		}
+	case *SwitchStmt:
+		return i.execSwitch(s)
+	case *DefStmt:
+		i.functions[s.Name] = &Function{Name: s.Name, Inline: true}
+	case *BuiltinStmt:
+		return i.execBuiltin(s)
 	}
+	return nil
 }
 
-func parseExpression(expr string, variables map[string]interface{}) interface{} {
-	expr = strings.ReplaceAll(expr, " ", "")
-	parts := strings.Split(expr, "+")
-	if len(parts) == 2 {
-		left := getValue(parts[0], variables)
-		right := getValue(parts[1], variables)
-		if leftFloat, ok := left.(float64); ok {
-			if rightFloat, ok := right.(float64); ok {
-				return leftFloat + rightFloat
-			}
-		}
-		return left.(int) + right.(int)
+// condHolds вычисляет условие if/while и приводит его к логическому значению
+func (i *Interpreter) condHolds(cond Expr) (bool, error) {
+	value, err := i.eval(cond)
+	if err != nil {
+		return false, err
 	}
-	parts = strings.Split(expr, "-")
-	if len(parts) == 2 {
-		left := getValue(parts[0], variables)
-		right := getValue(parts[1], variables)
-		if leftFloat, ok := left.(float64); ok {
-			if rightFloat, ok := right.(float64); ok {
-				return leftFloat - rightFloat
-			}
-		}
-		return left.(int) - right.(int)
+	return truthy(value), nil
+}
+
+// execSwitch выполняет первую ветку case, значение которой совпало, иначе default
+func (i *Interpreter) execSwitch(s *SwitchStmt) error {
+	subject, ok := i.scope.Lookup(s.Var)
+	if !ok {
+		return at(s, newError(KindName, "переменная %s не определена", s.Var))
 	}
-	parts = strings.Split(expr, "*")
-	if len(parts) == 2 {
-		left := getValue(parts[0], variables)
-		right := getValue(parts[1], variables)
-		if leftFloat, ok := left.(float64); ok {
-			if rightFloat, ok := right.(float64); ok {
-				return leftFloat * rightFloat
-			}
+	for _, c := range s.Cases {
+		value, err := i.eval(c.Value)
+		if err != nil {
+			return err
 		}
-		return left.(int) * right.(int)
-	}
-	parts = strings.Split(expr, "/")
-	if len(parts) == 2 {
-		left := getValue(parts[0], variables)
-		right := getValue(parts[1], variables)
-		if leftFloat, ok := left.(float64); ok {
-			if rightFloat, ok := right.(float64); ok {
-				if rightFloat == 0 {
-					fmt.Println("Ошибка: деление на ноль")
-					return 0
-				}
-				return leftFloat / rightFloat
-			}
+		if valuesEqual(subject, value) {
+			return i.runBlock(c.Body)
 		}
-		if right.(int) == 0 {
-			fmt.Println("Ошибка: деление на ноль")
-			return 0
+	}
+	if s.Default != nil {
+		return i.runBlock(s.Default.Body)
+	}
+	return nil
+}
+
+// runBlock выполняет последовательность операторов. Ошибка или сигнал управления
+// прерывают блок и передаются выше.
+func (i *Interpreter) runBlock(stmts []Stmt) error {
+	if err := i.enter(); err != nil {
+		return err
+	}
+	defer i.leave()
+	for _, stmt := range stmts {
+		if err := i.ExecuteStatement(stmt); err != nil {
+			return err
 		}
-		return left.(int) / right.(int)
 	}
-	return getValue(expr, variables)
+	return nil
 }
 
-func getValue(part string, variables map[string]interface{}) interface{} {
-	if val, ok := variables[part]; ok {
-		return val
+// execInput читает строку ввода в переменную; solve.input требует число
+func (i *Interpreter) execInput(s *InputStmt) error {
+	switch s.Mode {
+	case InputNumber:
+		fmt.Printf("Введите число для %s: ", s.Name)
+	case InputText:
+		fmt.Printf("Введите текст для %s: ", s.Name)
+	default:
+		fmt.Printf("Введите значение для %s: ", s.Name)
 	}
-	if num, err := strconv.Atoi(part); err == nil {
-		return num
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if s.Mode != InputNumber {
+		i.scope.Assign(s.Name, Str(input))
+		return nil
 	}
-	if num, err := strconv.ParseFloat(part, 64); err == nil {
-		return num
+	num, ok := parseNumber(input)
+	if !ok {
+		return newError(KindValue, "для %s ожидалось число, введено %q", s.Name, input)
 	}
-	return part
+	i.scope.Assign(s.Name, num)
+	return nil
 }
 
-func parseTextExpression(expr string, variables map[string]interface{}) string {
-	expr = strings.ReplaceAll(expr, " ", "")
-	parts := strings.Split(expr, "+")
-	var result string
-	for _, part := range parts {
-		if val, ok := variables[part].(string); ok {
-			if result != "" {
-				result += " "
-			}
-			result += val
-		} else {
-			fmt.Println("Ошибка: переменная не является текстом")
-		}
+// execBuiltin выполняет встроенную команду; её результат сохраняется в lastResult
+func (i *Interpreter) execBuiltin(s *BuiltinStmt) error {
+	args, err := i.evalArgs(s.Args)
+	if err != nil {
+		return err
+	}
+	result, err := i.callBuiltin(s.Cmd, args)
+	if err != nil {
+		return err
 	}
-	return result
+	if result != nil {
+		i.lastResult = result
+	}
+	return nil
 }
 
-func (i *Interpreter) ExecuteProgram(program string) {
-	lines := strings.Split(program, "\n")
-	var currentFunction string
+// callBuiltin выполняет встроенную команду с вычисленными аргументами.
+// Команды, которые только изменяют свои аргументы (list_append, dict_set...),
+// возвращают nil.
+func (i *Interpreter) callBuiltin(cmd Command, args []Value) (Value, error) {
+	name := cmd.Name
 
-	for _, line := range lines {
-		if idx := strings.Index(line, "//"); idx != -1 {
-			line = line[:idx]
+	switch cmd.ID {
+	case 13, 26: // text.length, len
+		switch val := args[0].(type) {
+		case *List:
+			if cmd.ID == 26 {
+				return Int(len(val.Items)), nil
+			}
+		case *Dict:
+			if cmd.ID == 26 {
+				return Int(val.Len()), nil
+			}
+		}
+		val, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		return Int(utf8.RuneCountInString(val)), nil
+	case 14, 32, 68: // text.upper, lower, upper
+		val, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.ID == 32 {
+			return Str(strings.ToLower(val)), nil
+		}
+		return Str(strings.ToUpper(val)), nil
+	case 15, 18: // abs, round
+		if d, ok := args[0].(Decimal); ok {
+			if cmd.ID == 18 {
+				return d.Round(0, i.decimal.Rounding), nil
+			}
+			return Decimal{new(big.Int).Abs(d.unscaled), d.scale}, nil
+		}
+		return callMath(name, args)
+	case 16, 17, 19, 20, 21, 22, 23, 24: // sqrt, pow, sin, cos, tan, log, log10, random
+		return callMath(name, args)
+	case 25: // randint
+		min, err := expectInt(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		max, err := expectInt(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		if max < min {
+			return nil, newError(KindValue, "randint: нижняя граница %d больше верхней %d", min, max)
+		}
+		return Int(randomBetween(min, max)), nil
+	case 27: // substr
+		val, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		start, err := expectInt(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		length, err := expectInt(args[2], name)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(val)
+		if start < 0 || start >= len(runes) || length < 0 {
+			return nil, newError(KindIndex, "substr: неверные индексы %d и %d", start, length)
+		}
+		if length > len(runes)-start {
+			length = len(runes) - start
+		}
+		return Str(runes[start : start+length]), nil
+	case 28: // find
+		str, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		// Позиция в символах, как у len и индексации строк
+		idx := strings.Index(str, sub)
+		if idx < 0 {
+			return Int(-1), nil
+		}
+		return Int(utf8.RuneCountInString(str[:idx])), nil
+	case 29: // replace
+		str, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		old, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		new, err := expectString(args[2], name)
+		if err != nil {
+			return nil, err
+		}
+		return Str(strings.Replace(str, old, new, -1)), nil
+	case 30: // split
+		str, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		list := NewList()
+		for _, part := range strings.Split(str, sep) {
+			list.Items = append(list.Items, Str(part))
+		}
+		return list, nil
+	case 31: // join
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(list.Items))
+		for idx, item := range list.Items {
+			parts[idx] = item.String()
+		}
+		return Str(strings.Join(parts, sep)), nil
+	case 65, 66, 67: // contains, starts_with, ends_with
+		if list, ok := args[0].(*List); ok && cmd.ID == 65 {
+			return Bool(listIndexOf(list, args[1]) >= 0), nil
+		}
+		str, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		switch cmd.ID {
+		case 65:
+			return Bool(strings.Contains(str, sub)), nil
+		case 66:
+			return Bool(strings.HasPrefix(str, sub)), nil
+		default:
+			return Bool(strings.HasSuffix(str, sub)), nil
+		}
+	case 40: // file.read
+		content, err := os.ReadFile(args[0].String())
+		if err != nil {
+			return nil, newError(KindIO, "не удалось прочитать файл: %v", err)
 		}
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+		return Str(content), nil
+	case 41: // file.write
+		if content, ok := i.lastResult.(Str); ok {
+			err := os.WriteFile(args[0].String(), []byte(content), 0644)
+			if err != nil {
+				return nil, newError(KindIO, "не удалось записать файл: %v", err)
+			}
+		}
+	case 44: // array_create
+		size, err := expectInt(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, newError(KindValue, "array_create: отрицательный размер %d", size)
+		}
+		list := &List{Items: make([]Value, size)}
+		for idx := range list.Items {
+			list.Items[idx] = Nil
+		}
+		return list, nil
+	case 45, 46, 49: // array_set, array_get, list_get
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		index, err := expectInt(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(list.Items) {
+			return nil, newError(KindIndex, "индекс %d вне диапазона", index)
+		}
+		if cmd.ID != 45 {
+			return list.Items[index], nil
+		}
+		list.Items[index] = args[2]
+	case 47: // list_create
+		return NewList(), nil
+	case 48: // list_append
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, args[1])
+	case 80: // list_insert
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		index, err := expectInt(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		return nil, listInsert(list, index, args[2])
+	case 81: // list_remove
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		idx := listIndexOf(list, args[1])
+		if idx < 0 {
+			return nil, newError(KindValue, "list_remove: значение %s не найдено в списке", repr(args[1]))
+		}
+		list.Items = append(list.Items[:idx], list.Items[idx+1:]...)
+	case 82, 83: // list_pop
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		index := len(list.Items) - 1
+		if cmd.ID == 83 {
+			if index, err = expectInt(args[1], name); err != nil {
+				return nil, err
+			}
+		}
+		return listPop(list, index)
+	case 84: // list_slice
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		start, err := expectInt(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		end, err := expectInt(args[2], name)
+		if err != nil {
+			return nil, err
+		}
+		return listSlice(list, start, end)
+	case 85: // list_index_of
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		return Int(listIndexOf(list, args[1])), nil
+	case 86, 87: // list_reverse, list_sort
+		list, err := expectList(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.ID == 87 {
+			return nil, listSort(list)
+		}
+		listReverse(list)
+	case 50: // dict_create
+		return NewDict(), nil
+	case 51: // dict_set
+		dict, err := expectDict(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		key, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		dict.Set(key, args[2])
+	case 52, 91, 92, 93: // dict_get, dict_has_key, dict_delete, dict_get с умолчанием
+		dict, err := expectDict(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		key, err := expectString(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.ID == 92 {
+			if !dict.Delete(key) {
+				return nil, newError(KindKey, "ключ %q не найден", key)
+			}
+			return nil, nil
 		}
+		val, ok := dict.Get(key)
+		switch {
+		case cmd.ID == 91:
+			return Bool(ok), nil
+		case !ok && cmd.ID == 93:
+			return args[2], nil
+		case !ok:
+			return nil, newError(KindKey, "ключ %q не найден", key)
+		}
+		return val, nil
+	case 88, 89, 90: // dict_keys, dict_values, dict_items
+		dict, err := expectDict(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		list := NewList()
+		for _, key := range dict.Keys() {
+			val, _ := dict.Get(key)
+			switch cmd.ID {
+			case 88:
+				list.Items = append(list.Items, Str(key))
+			case 89:
+				list.Items = append(list.Items, val)
+			default:
+				list.Items = append(list.Items, NewList(Str(key), val))
+			}
+		}
+		return list, nil
+	case 94: // dict_merge
+		dict, err := expectDict(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		other, err := expectDict(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		merged := NewDict()
+		for _, src := range []*Dict{dict, other} {
+			for _, key := range src.Keys() {
+				val, _ := src.Get(key)
+				merged.Set(key, val)
+			}
+		}
+		return merged, nil
+	case 53: // time
+		return Str(time.Now().Format("15:04:05")), nil
+	case 54: // date
+		return Str(time.Now().Format("2006-01-02")), nil
+	case 55: // env
+		return Str(os.Getenv(args[0].String())), nil
+	case 75: // int
+		return convertInt(args[0])
+	case 76: // float
+		return convertFloat(args[0])
+	case 77: // str
+		return Str(args[0].String()), nil
+	case 78: // bool
+		return convertBool(args[0])
+	case 79: // type_of
+		return Str(typeName(args[0])), nil
+	case 74: // is_nil
+		return Bool(args[0] == Nil), nil
+	case 69: // decimal
+		return makeDecimal(args[0])
+	case 70: // round (x, n)
+		places, err := expectInt(args[1], name)
+		if err != nil {
+			return nil, err
+		}
+		return roundValue(args[0], places, i.decimal)
+	case 71: // decimal.precision
+		precision, err := expectInt(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		if precision < 0 {
+			return nil, newError(KindValue, "decimal.precision: число знаков не может быть отрицательным: %d", precision)
+		}
+		i.decimal.Precision = precision
+	case 72: // decimal.rounding
+		modeName, err := expectString(args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		mode, err := parseRoundingMode(modeName)
+		if err != nil {
+			return nil, err
+		}
+		i.decimal.Rounding = mode
+	default:
+		return nil, newError(KindName, "команда %s не может быть вызвана как функция", name)
+	}
+	return nil, nil
+}
 
-		lineLower := strings.ToLower(line)
+// randomBetween возвращает случайное число от min до max включительно.
+// Ширина диапазона считается в uint64: max-min+1 может не поместиться в int.
+func randomBetween(min, max int) int {
+	span := uint64(max) - uint64(min)
+	if span < math.MaxInt64 {
+		return min + int(rand.Int63n(int64(span)+1))
+	}
+	// Диапазон шире int64: выборка с отбрасыванием, подходит не меньше половины значений
+	for {
+		if v := rand.Uint64(); v <= span {
+			return min + int(v)
+		}
+	}
+}
 
-		switch {
-		case strings.HasPrefix(lineLower, "function ("):
-			currentFunction = strings.Trim(strings.TrimPrefix(line, "Function ("), ")")
-			i.functions[currentFunction] = []string{}
-		case lineLower == "memory start (":
-			continue
-		case lineLower == ")":
-			currentFunction = ""
-		case currentFunction != "":
-			i.functions[currentFunction] = append(i.functions[currentFunction], line)
-		default:
-			i.ExecuteStatement(line)
+// ExecuteProgram разбирает и выполняет программу. filename используется в сообщениях
+// об ошибках. Выполнение останавливается на первой ошибке, она возвращается как *ClashError.
+func (i *Interpreter) ExecuteProgram(filename, program string) (err error) {
+	defer func() {
+		// Паника внутри интерпретатора не должна доходить до пользователя
+		if r := recover(); r != nil {
+			err = newError(KindInternal, "%v", r)
 		}
+		if err != nil {
+			err = withSource(err, filename, program)
+		}
+	}()
+
+	prog, err := i.Parse(program)
+	if err != nil {
+		return err
+	}
+	for _, warning := range prog.Warnings {
+		fmt.Fprintln(os.Stderr, "предупреждение:", withSource(warning, filename, program).(*ClashError).Diagnostic())
+	}
+	err = i.runBlock(prog.Stmts)
+	if isSignal(err) {
+		return newError(KindInternal, "%v", err)
 	}
+	return err
 }
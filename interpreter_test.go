@@ -0,0 +1,396 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestInterpreter создаёт интерпретатор с командами из commands.json
+func newTestInterpreter(t *testing.T) *Interpreter {
+	t.Helper()
+	interp, err := NewInterpreter()
+	if err != nil {
+		t.Fatalf("NewInterpreter: %v", err)
+	}
+	return interp
+}
+
+// runProgram выполняет программу и возвращает то, что она вывела
+func runProgram(t *testing.T, src string) (string, error) {
+	t.Helper()
+	return runWith(t, newTestInterpreter(t), src)
+}
+
+// runWith выполняет программу на заданном интерпретаторе, перехватывая stdout
+func runWith(t *testing.T, interp *Interpreter, src string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	runErr := interp.ExecuteProgram("test.clash", src)
+	w.Close()
+	os.Stdout = stdout
+	return <-done, runErr
+}
+
+// mustRun выполняет программу, которая должна завершиться без ошибки
+func mustRun(t *testing.T, src string) string {
+	t.Helper()
+	out, err := runProgram(t, src)
+	if err != nil {
+		t.Fatalf("программа завершилась ошибкой: %v\n%s", err, src)
+	}
+	return out
+}
+
+// errorKind возвращает вид ошибки ClashLang или завершает тест
+func errorKind(t *testing.T, err error) ErrorKind {
+	t.Helper()
+	ce, ok := err.(*ClashError)
+	if !ok {
+		t.Fatalf("ожидалась *ClashError, получено %T: %v", err, err)
+	}
+	return ce.Kind
+}
+
+func TestExecuteProgramWalksAST(t *testing.T) {
+	out := mustRun(t, strings.Join([]string{
+		"solve (2 + 3)",
+		"solve.out = x",
+		"print (x)",
+		"text (\"a\")",
+		"text.out = s",
+		"print (s)",
+	}, "\n"))
+	if out != "5\na\n" {
+		t.Errorf("вывод = %q, ожидалось %q", out, "5\na\n")
+	}
+}
+
+func TestNestedBlocksExecute(t *testing.T) {
+	out := mustRun(t, strings.Join([]string{
+		"for i from 1 to 3 {",
+		"  for j from 1 to 2 {",
+		"    if j == 2 {",
+		"      print (i * 10 + j)",
+		"    }",
+		"  }",
+		"}",
+		"print (0)",
+	}, "\n"))
+	if want := "12\n22\n32\n0\n"; out != want {
+		t.Errorf("вывод = %q, ожидалось %q", out, want)
+	}
+}
+
+// programCase — программа и её ожидаемый вывод
+type programCase struct {
+	src  string
+	want string
+}
+
+func checkPrograms(t *testing.T, tests []programCase) {
+	t.Helper()
+	for _, tt := range tests {
+		out, err := runProgram(t, tt.src)
+		if err != nil {
+			t.Errorf("ошибка %v в программе\n%s", err, tt.src)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("вывод %q, ожидалось %q в программе\n%s", out, tt.want, tt.src)
+		}
+	}
+}
+
+func TestElseBranches(t *testing.T) {
+	const chain = `for x from 1 to 4 {
+  if x == 1 {
+    print ("один")
+  } else if x == 2 {
+    print ("два")
+  } else if x == 3 {
+    print ("три")
+  } else {
+    print ("много")
+  }
+}`
+	checkPrograms(t, []programCase{
+		{chain, "один\nдва\nтри\nмного\n"},
+		{"if false {\n  print (1)\n} else {\n  if true {\n    print (2)\n  } else {\n    print (3)\n  }\n}", "2\n"},
+		{"if false {\n  print (1)\n} else if false {\n  print (2)\n}\nprint (3)", "3\n"},
+	})
+}
+
+func TestConditionsInLoops(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"a = 1\nb = 3\nwhile a < b and b != 0 {\n  a = a + 1\n}\nprint (a)", "3\n"},
+		{"s = \"x\"\nif s == \"x\" or s == \"y\" {\n  print (s)\n}", "x\n"},
+		{"n = 0\ndo {\n  n = n + 1\n}\nwhile n * n < 10\nprint (n)", "4\n"},
+		{"f = 0.5\nif f > 0.25 and not f >= 1 {\n  print (\"ok\")\n}", "ok\n"},
+	})
+}
+
+func TestFunctions(t *testing.T) {
+	const add = "def add(a, b) {\n  return a + b\n}\n"
+	checkPrograms(t, []programCase{
+		{add + "print (add(2, 3))", "5\n"},
+		{add + "solve (add(1, 2) * 10)\nsolve.out = r\nprint (r)", "30\n"},
+		{add + "x = add(add(1, 1), 3)\nprint (x)", "5\n"},
+		{add + "jump (add(4, 4))\nsolve.out = r\nprint (r)", "8\n"},
+		{add + "function_call (add(1, 1))\nsolve.out = r\nprint (r)", "2\n"},
+		{"def hello() {\n  print (\"hi\")\n}\njump (hello)", "hi\n"},
+		{"def none() {\n  return\n}\nprint (none())", "nil\n"},
+	})
+}
+
+func TestFunctionArity(t *testing.T) {
+	_, err := runProgram(t, "def add(a, b) {\n  return a + b\n}\nprint (add(1))")
+	if err == nil {
+		t.Fatal("ожидалась ошибка числа аргументов")
+	}
+}
+
+func TestReturnOutsideFunction(t *testing.T) {
+	if _, err := runProgram(t, "return 1"); err == nil {
+		t.Error("return вне функции должен быть ошибкой")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	checkPrograms(t, []programCase{
+		// Параметр и присваивание в функции не трогают переменную вызывающего
+		{"x = 1\ndef f(x) {\n  x = x + 100\n  return x\n}\nprint (f(2))\nprint (x)", "102\n1\n"},
+		{"x = 1\ndef g() {\n  x = 7\n  return x\n}\nprint (g())\nprint (x)", "7\n1\n"},
+		// Локальные переменные функции не видны после вызова
+		{"def f() {\n  y = 5\n}\njump (f)\nprint (exists(y))", "false\n"},
+		// Переменная цикла живёт только в цикле, присваивания в теле — снаружи видны
+		{"for i from 1 to 2 {\n  k = i\n}\nprint (exists(i))\nprint (k)", "false\n2\n"},
+		// Вызовы не делят локальные переменные между собой
+		{"def count(n) {\n  if n == 0 {\n    return 0\n  }\n  m = n\n  r = count(n - 1)\n  return m + r\n}\nprint (count(4))", "10\n"},
+	})
+}
+
+func TestSwitchDoesNotLeakIntoMemory(t *testing.T) {
+	out := mustRun(t, "x = 3\nswitch x {\ncase 3:\n  print (\"три\")\n}\nmemory out")
+	if want := "три\n1) 3\n"; out != want {
+		t.Errorf("вывод = %q, ожидалось %q", out, want)
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"def fact(n) {\n  if n <= 1 {\n    return 1\n  }\n  return n * fact(n - 1)\n}\nprint (fact(10))", "3628800\n"},
+		{"def even(n) {\n  if n == 0 {\n    return true\n  }\n  return odd(n - 1)\n}\ndef odd(n) {\n  if n == 0 {\n    return false\n  }\n  return even(n - 1)\n}\nprint (even(10))", "true\n"},
+	})
+}
+
+func TestStackOverflow(t *testing.T) {
+	const endless = "def f(n) {\n  return f(n + 1)\n}\nsolve (f(0))"
+	for _, depth := range []int{50, MaxDepthLimit} {
+		interp := newTestInterpreter(t)
+		if err := interp.SetMaxDepth(depth); err != nil {
+			t.Fatal(err)
+		}
+		_, err := runWith(t, interp, endless)
+		if err == nil {
+			t.Fatalf("глубина %d: ожидалось переполнение стека", depth)
+		}
+		if kind := errorKind(t, err); kind != KindStackOverflow {
+			t.Errorf("глубина %d: ошибка %v, ожидалось переполнение стека", depth, err)
+		}
+		if !strings.Contains(err.Error(), "цепочка вызовов") {
+			t.Errorf("глубина %d: в сообщении нет цепочки вызовов: %v", depth, err)
+		}
+	}
+}
+
+func TestDeepBlocksOverflow(t *testing.T) {
+	// Рекурсивный вызов внутри 60 вложенных if: глубина вызовов в пределах
+	// MaxDepthLimit, но стек Go растёт с каждым блоком
+	const nested = 60
+	src := "def r(n) {\n  if n == 0 {\n    return 0\n  }\n" +
+		strings.Repeat("if true {\n", nested) + "return r(n - 1)\n" + strings.Repeat("}\n", nested) +
+		"}\nprint (r(" + strconv.Itoa(MaxDepthLimit) + "))"
+	interp := newTestInterpreter(t)
+	if err := interp.SetMaxDepth(MaxDepthLimit); err != nil {
+		t.Fatal(err)
+	}
+	_, err := runWith(t, interp, src)
+	if err == nil {
+		t.Fatal("ожидалось переполнение стека")
+	}
+	if kind := errorKind(t, err); kind != KindStackOverflow {
+		t.Errorf("ошибка %v, ожидалось переполнение стека", err)
+	}
+}
+
+func TestSetMaxDepthBounds(t *testing.T) {
+	interp := newTestInterpreter(t)
+	for _, depth := range []int{0, -1, MaxDepthLimit + 1} {
+		if err := interp.SetMaxDepth(depth); err == nil {
+			t.Errorf("SetMaxDepth(%d): ожидалась ошибка", depth)
+		}
+	}
+}
+
+func TestBreakContinue(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"for i from 1 to 10 {\n  if i == 4 {\n    break\n  }\n  print (i)\n}", "1\n2\n3\n"},
+		{"for i from 1 to 5 {\n  if i % 2 == 0 {\n    continue\n  }\n  print (i)\n}", "1\n3\n5\n"},
+		{"n = 0\nwhile true {\n  n = n + 1\n  if n > 2 {\n    break\n  }\n}\nprint (n)", "3\n"},
+		{"n = 0\ndo {\n  n = n + 1\n  if n < 3 {\n    continue\n  }\n  print (n)\n}\nwhile n < 4", "3\n4\n"},
+		// break выходит только из ближайшего цикла
+		{"for i from 1 to 2 {\n  for j from 1 to 3 {\n    if j == 2 {\n      break\n    }\n    print (i * 10 + j)\n  }\n}", "11\n21\n"},
+		// break из switch внутри цикла
+		{"for i from 1 to 3 {\n  switch i {\n  case 2:\n    break\n  }\n  print (i)\n}", "1\n"},
+	})
+}
+
+func TestBuiltinsShareValueModel(t *testing.T) {
+	checkPrograms(t, []programCase{
+		// join принимает и список из split, и список из list_create
+		{"parts = split(\"a,b\", \",\")\nprint (join(parts, \"-\"))", "a-b\n"},
+		{"xs = list_create()\nlist_append (xs, \"x\")\nlist_append (xs, 2)\nprint (join(xs, \"+\"))", "x+2\n"},
+		{"d = dict_create()\ndict_set (d, \"k\", [1])\nprint (d)", "{\"k\": [1]}\n"},
+		{"xs = [1]\nlist_append (xs, xs)\nprint (xs)", "[1, [...]]\n"},
+	})
+}
+
+func TestIfAcceptsBoolExpressions(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"ok = contains(\"abc\", \"c\")\nif ok {\n  print (\"да\")\n}", "да\n"},
+		{"done = false\nwhile not done {\n  print (1)\n  done = true\n}", "1\n"},
+	})
+}
+
+func TestStringLiterals(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{`print ("a\tb")`, "a\tb\n"},
+		{`print (replace("a-b-c", "-", "+"))`, "a+b+c\n"},
+		{`print (find("hello", "l"))`, "2\n"},
+		{`print ("http://x.org") // комментарий`, "http://x.org\n"},
+		{`print ("" + "x" + "")`, "x\n"},
+	})
+}
+
+func TestAssignment(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"a = 2\nb = 3\nc = a + b * 2\nprint (c)", "8\n"},
+		{"s = \"abc\"\nname = upper(s)\nprint (name)", "ABC\n"},
+		{"n = len(\"привет\") + 1\nprint (n)", "7\n"},
+		{"t = text.upper(\"x\")\nprint (t)", "X\n"},
+		// Старые формы через lastResult продолжают работать, а присваивание его не трогает
+		{"solve (40 + 2)\nx = 1\nsolve.out = r\nprint (r)", "42\n"},
+		{"x = 1\nx = x + 1\nprint (x)", "2\n"},
+	})
+}
+
+func TestBigIntFactorial(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"f = 1\nfor i from 1 to 30 {\n  f = f * i\n}\nprint (f)", "265252859812191058636308480000000\n"},
+		// Счётчик for не переполняется на границе int
+		{"for i from 9223372036854775806 to 9223372036854775807 {\n  print (i)\n}", "9223372036854775806\n9223372036854775807\n"},
+		{"for i from 3 to 1 {\n  print (i)\n}\nprint (0)", "0\n"},
+	})
+}
+
+func TestDecimalContext(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"decimal.precision (2)\nprint (2d / 3d)", "0.67\n"},
+		{"decimal.precision (2)\ndecimal.rounding (down)\nprint (2d / 3d)", "0.66\n"},
+		{"decimal.rounding (\"ceiling\")\nprint (round(1.21d, 1))", "1.3\n"},
+		// Шаблон округляет decimal так же, как round
+		{"decimal.rounding (half_up)\nx = 2.5d\ny = 0.125d\nprint_formatted (\"{round(x, 0)} {x:.0f} {y:.2f}\")", "3 3 0.13\n"},
+		{"decimal.rounding (floor)\nx = -1.21d\nprint_formatted (\"{x:.1f} {round(x, 1)}\")", "-1.3 -1.3\n"},
+	})
+	if _, err := runProgram(t, "decimal.rounding (sideways)"); err == nil {
+		t.Error("неизвестный режим округления должен быть ошибкой")
+	}
+}
+
+func TestRandomWithoutArguments(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"random ()\nx = random()\nsolve (random())\nprint (x >= 0 and x < 1)", "true\n"},
+	})
+}
+
+func TestUndefinedVariable(t *testing.T) {
+	_, err := runProgram(t, "x = 1\nprint (y)")
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	ce := err.(*ClashError)
+	if ce.Kind != KindName || ce.Pos.Line != 2 || !strings.Contains(ce.Message, "y") {
+		t.Errorf("ошибка %v, ожидалось неопределённое имя y в строке 2", ce)
+	}
+	checkPrograms(t, []programCase{
+		{"x = nil\nprint (exists(x))\nprint (is_nil(x))", "true\ntrue\n"},
+		{"exists (x)\nsolve.out = r\nprint (r)", "false\n"},
+	})
+}
+
+func TestBareParams(t *testing.T) {
+	t.Setenv("CLASH_TEST_VAR", "значение")
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	checkPrograms(t, []programCase{
+		// Неопределённое имя в env, file.read/write и decimal.rounding — текст
+		{"env (CLASH_TEST_VAR)\nsolve.out = v\nprint (v)", "значение\n"},
+		{"decimal.precision (1)\ndecimal.rounding (up)\nprint (1d / 3d)", "0.4\n"},
+		{"text (\"запись\")\nfile.write (" + file + ")\nfile.read (" + file + ")\ntext.out = s\nprint (s)", "запись\n"},
+		// Переменная и строка читаются так же, как в форме вызова
+		{"name = \"CLASH_TEST_VAR\"\nenv (name)\nsolve.out = v\nprint (v == env(name))", "true\n"},
+		{"env (\"CLASH_TEST_VAR\")\nsolve.out = v\nprint (v)", "значение\n"},
+		{"mode = \"up\"\ndecimal.precision (1)\ndecimal.rounding (mode)\nprint (1d / 3d)", "0.4\n"},
+		{"path = \"" + file + "\"\nfile.read (path)\ntext.out = s\nprint (s)", "запись\n"},
+	})
+}
+
+func TestBarePaths(t *testing.T) {
+	interp := newTestInterpreter(t)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"data/notes": "a/b", "my-notes": "a-b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	src := "file.read (data/notes)\ntext.out = s\nprint (s)\nfile.read (my-notes)\ntext.out = s\nprint (s)"
+	out, err := runWith(t, interp, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "a/b\na-b\n" {
+		t.Errorf("вывод %q, ожидалось %q", out, "a/b\na-b\n")
+	}
+}
+
+func TestIndexedAssignment(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"xs = [1, 2, 3]\nxs[0] = xs[0] + 10\nprint (xs)", "[11, 2, 3]\n"},
+		{"m = {\"a\": [1, 2, 3]}\nm[\"a\"][2] = 99\nm[\"new\"] = {}\nm[\"new\"][\"k\"] = true\nprint (m)", "{\"a\": [1, 2, 99], \"new\": {\"k\": true}}\n"},
+		{"i = 1\nxs = [0, 0]\nxs[i] = \"x\"\nprint (xs)", "[0, \"x\"]\n"},
+		// Списки общие: изменение через одну переменную видно через другую
+		{"a = [1]\nb = a\nb[0] = 2\nprint (a)", "[2]\n"},
+	})
+	for _, src := range []string{"xs = [1]\nxs[1] = 0", "s = \"ab\"\ns[0] = \"c\"", "x = 1\nx[0] = 1", "d = {}\nd[1] = 2"} {
+		if _, err := runProgram(t, src); err == nil {
+			t.Errorf("ожидалась ошибка в программе\n%s", src)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind — вид лексемы
+type TokenKind int
+
+const (
+	TokEOF TokenKind = iota
+	TokNewline
+	TokIdent
+	TokInt
+	TokFloat
+	TokDecimal
+	TokString
+	TokPunct
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokEOF:
+		return "конец файла"
+	case TokNewline:
+		return "конец строки"
+	case TokIdent:
+		return "идентификатор"
+	case TokInt:
+		return "целое число"
+	case TokFloat:
+		return "дробное число"
+	case TokDecimal:
+		return "десятичное число"
+	case TokString:
+		return "строка"
+	case TokPunct:
+		return "символ"
+	}
+	return "неизвестная лексема"
+}
+
+// Token — лексема исходного текста
+type Token struct {
+	Kind   TokenKind
+	Text   string // исходный текст лексемы; у строки — вместе с кавычками
+	Value  string // у строки — содержимое с раскрытыми escape-последовательностями
+	Pos    Pos
+	Offset int // смещение в байтах от начала исходного текста
+}
+
+func (t Token) String() string {
+	if t.Kind == TokNewline || t.Kind == TokEOF {
+		return t.Kind.String()
+	}
+	return fmt.Sprintf("%q", t.Text)
+}
+
+// Lexer разбивает исходный текст на лексемы
+type Lexer struct {
+	src    string
+	offset int
+	line   int
+	col    int
+}
+
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src, line: 1, col: 1}
+}
+
+// Tokenize возвращает все лексемы текста, завершая их TokEOF
+func Tokenize(src string) ([]Token, error) {
+	lx := NewLexer(src)
+	var tokens []Token
+	for {
+		tok, err := lx.Next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Kind == TokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (lx *Lexer) peek(n int) rune {
+	off := lx.offset
+	for ; n > 0 && off < len(lx.src); n-- {
+		_, size := utf8.DecodeRuneInString(lx.src[off:])
+		off += size
+	}
+	if off >= len(lx.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(lx.src[off:])
+	return r
+}
+
+func (lx *Lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(lx.src[lx.offset:])
+	lx.offset += size
+	if r == '\n' {
+		lx.line++
+		lx.col = 1
+	} else {
+		lx.col++
+	}
+	return r
+}
+
+// Next возвращает очередную лексему
+func (lx *Lexer) Next() (Token, error) {
+	for lx.offset < len(lx.src) {
+		r := lx.peek(0)
+		if r == ' ' || r == '\t' || r == '\r' {
+			lx.advance()
+			continue
+		}
+		if r == '/' && lx.peek(1) == '/' {
+			// Комментарий до конца строки
+			for lx.offset < len(lx.src) && lx.peek(0) != '\n' {
+				lx.advance()
+			}
+			continue
+		}
+		break
+	}
+
+	start := Token{Pos: Pos{Line: lx.line, Col: lx.col}, Offset: lx.offset}
+	if lx.offset >= len(lx.src) {
+		start.Kind = TokEOF
+		return start, nil
+	}
+
+	r := lx.peek(0)
+	switch {
+	case r == '\n':
+		lx.advance()
+		start.Kind = TokNewline
+		start.Text = "\n"
+	case isDigit(r):
+		start.Kind = TokInt
+		for isDigit(lx.peek(0)) {
+			lx.advance()
+		}
+		if lx.peek(0) == '.' && isDigit(lx.peek(1)) {
+			start.Kind = TokFloat
+			lx.advance()
+			for isDigit(lx.peek(0)) {
+				lx.advance()
+			}
+		}
+		// Суффикс d — точное десятичное число: 0.1d
+		if lx.peek(0) == 'd' && !isIdentStart(lx.peek(1)) && !isDigit(lx.peek(1)) {
+			lx.advance()
+			start.Kind = TokDecimal
+		}
+		start.Text = lx.src[start.Offset:lx.offset]
+	case r == '"':
+		value, err := lx.readString()
+		if err != nil {
+			return start, err
+		}
+		start.Kind = TokString
+		start.Text = lx.src[start.Offset:lx.offset]
+		start.Value = value
+	case isIdentStart(r):
+		for isIdentStart(lx.peek(0)) || isDigit(lx.peek(0)) {
+			lx.advance()
+		}
+		start.Kind = TokIdent
+		start.Text = lx.src[start.Offset:lx.offset]
+	default:
+		lx.advance()
+		if isTwoCharOperator(r, lx.peek(0)) {
+			lx.advance()
+		}
+		start.Kind = TokPunct
+		start.Text = lx.src[start.Offset:lx.offset]
+	}
+	return start, nil
+}
+
+// readString читает строку в двойных кавычках; // внутри неё — обычный текст,
+// а не комментарий
+func (lx *Lexer) readString() (string, error) {
+	open := Pos{Line: lx.line, Col: lx.col}
+	lx.advance()
+	var b strings.Builder
+	for {
+		if lx.offset >= len(lx.src) || lx.peek(0) == '\n' {
+			return "", syntaxError(open, "незакрытая строка")
+		}
+		escPos := Pos{Line: lx.line, Col: lx.col}
+		r := lx.advance()
+		if r == '"' {
+			return b.String(), nil
+		}
+		if r != '\\' {
+			b.WriteRune(r)
+			continue
+		}
+		if lx.offset >= len(lx.src) {
+			return "", syntaxError(open, "незакрытая строка")
+		}
+		switch esc := lx.advance(); esc {
+		case 'n':
+			b.WriteRune('\n')
+		case 't':
+			b.WriteRune('\t')
+		case 'r':
+			b.WriteRune('\r')
+		case '"', '\\':
+			b.WriteRune(esc)
+		case 'u':
+			var code rune
+			for n := 0; n < 4; n++ {
+				digit, ok := hexDigit(lx.peek(0))
+				if !ok {
+					return "", syntaxError(escPos, "после \\u ожидается четыре шестнадцатеричные цифры")
+				}
+				lx.advance()
+				code = code*16 + digit
+			}
+			b.WriteRune(code)
+		default:
+			return "", syntaxError(escPos, "неизвестная escape-последовательность \\%c", esc)
+		}
+	}
+}
+
+func hexDigit(r rune) (rune, bool) {
+	switch {
+	case r >= '0' && r <= '9':
+		return r - '0', true
+	case r >= 'a' && r <= 'f':
+		return r - 'a' + 10, true
+	case r >= 'A' && r <= 'F':
+		return r - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// isTwoCharOperator распознаёт операторы сравнения из двух символов: == != <= >=
+func isTwoCharOperator(first, second rune) bool {
+	return second == '=' && (first == '=' || first == '!' || first == '<' || first == '>')
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
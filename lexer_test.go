@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		src   string
+		kinds []TokenKind
+		texts []string
+	}{
+		{"pow (a, 2)", []TokenKind{TokIdent, TokPunct, TokIdent, TokPunct, TokInt, TokPunct},
+			[]string{"pow", "(", "a", ",", "2", ")"}},
+		{"x >= 1.5", []TokenKind{TokIdent, TokPunct, TokFloat}, []string{"x", ">=", "1.5"}},
+		{"a != b == c <= d", []TokenKind{TokIdent, TokPunct, TokIdent, TokPunct, TokIdent, TokPunct, TokIdent},
+			[]string{"a", "!=", "b", "==", "c", "<=", "d"}},
+		{"print (x) // комментарий", []TokenKind{TokIdent, TokPunct, TokIdent, TokPunct}, []string{"print", "(", "x", ")"}},
+	}
+	for _, tt := range tests {
+		line := lineTokens(t, tt.src)
+		if len(line) != len(tt.kinds) {
+			t.Errorf("%q: %d лексем, ожидалось %d: %v", tt.src, len(line), len(tt.kinds), line)
+			continue
+		}
+		for idx, tok := range line {
+			if tok.Kind != tt.kinds[idx] || tok.Text != tt.texts[idx] {
+				t.Errorf("%q: лексема %d = %v %q, ожидалось %v %q", tt.src, idx, tok.Kind, tok.Text, tt.kinds[idx], tt.texts[idx])
+			}
+		}
+	}
+}
+
+func TestTokenizePositions(t *testing.T) {
+	tokens, err := Tokenize("a\n  bb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a, перевод строки, bb, EOF
+	if got := tokens[2].Pos; got != (Pos{Line: 2, Col: 3}) {
+		t.Errorf("позиция bb = %+v, ожидалось строка 2, столбец 3", got)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"\t\"x\"\\"`, "\t\"x\"\\"},
+		{`"\u043f\u0440"`, "пр"},
+		{`"http://example.com // не комментарий"`, "http://example.com // не комментарий"},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		line := lineTokens(t, tt.src)
+		if len(line) != 1 || line[0].Kind != TokString || line[0].Value != tt.want {
+			t.Errorf("%s: %v, ожидалась строка %q", tt.src, line, tt.want)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	for _, src := range []string{`"abc`, `"a\qb"`, `"\u12"`, "\"a\nb\""} {
+		if _, err := Tokenize(src); err == nil {
+			t.Errorf("%q: ожидалась ошибка", src)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import "sort"
+
+// concatLists — xs + ys: новый список, исходные не меняются
+func concatLists(left, right *List) *List {
+	items := make([]Value, 0, len(left.Items)+len(right.Items))
+	items = append(items, left.Items...)
+	return NewList(append(items, right.Items...)...)
+}
+
+// listInsert вставляет значение перед элементом index; index == len — в конец
+func listInsert(list *List, index int, val Value) error {
+	if index < 0 || index > len(list.Items) {
+		return newError(KindIndex, "индекс %d вне диапазона", index)
+	}
+	list.Items = append(list.Items, nil)
+	copy(list.Items[index+1:], list.Items[index:])
+	list.Items[index] = val
+	return nil
+}
+
+// listPop удаляет элемент index и возвращает его
+func listPop(list *List, index int) (Value, error) {
+	if len(list.Items) == 0 {
+		return nil, newError(KindIndex, "list_pop: список пуст")
+	}
+	if index < 0 || index >= len(list.Items) {
+		return nil, newError(KindIndex, "индекс %d вне диапазона", index)
+	}
+	val := list.Items[index]
+	list.Items = append(list.Items[:index], list.Items[index+1:]...)
+	return val, nil
+}
+
+// listIndexOf — номер первого элемента, равного val, или -1
+func listIndexOf(list *List, val Value) int {
+	for idx, item := range list.Items {
+		if valuesEqual(item, val) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// listSlice — элементы с start по end не включая, новым списком
+func listSlice(list *List, start, end int) (*List, error) {
+	if start < 0 || end > len(list.Items) || start > end {
+		return nil, newError(KindIndex, "list_slice: неверные индексы %d и %d", start, end)
+	}
+	return NewList(append([]Value(nil), list.Items[start:end]...)...), nil
+}
+
+// listReverse переворачивает список на месте
+func listReverse(list *List) {
+	for left, right := 0, len(list.Items)-1; left < right; left, right = left+1, right-1 {
+		list.Items[left], list.Items[right] = list.Items[right], list.Items[left]
+	}
+}
+
+// listSort сортирует на месте список чисел или список строк; сортировка
+// устойчивая, равные элементы сохраняют порядок
+func listSort(list *List) error {
+	// Сначала проверяем, что все элементы сравнимы, чтобы при ошибке
+	// список остался нетронутым
+	for _, item := range list.Items {
+		if _, ok := compareValues(list.Items[0], item); !ok {
+			return newError(KindType, "list_sort: нельзя сравнить %s и %s", typeName(list.Items[0]), typeName(item))
+		}
+	}
+	sort.SliceStable(list.Items, func(a, b int) bool {
+		cmp, _ := compareValues(list.Items[a], list.Items[b])
+		return cmp < 0
+	})
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestListLibrary(t *testing.T) {
+	checkExprs(t, []exprCase{
+		{"len([1, 2, 3])", TypeInt, "3"},
+		{`len({"a": 1})`, TypeInt, "1"},
+		{"[1, 2] + [3]", TypeList, "[1, 2, 3]"},
+		{"list_slice([1, 2, 3, 4], 1, 3)", TypeList, "[2, 3]"},
+		{"list_slice([1, 2], 2, 2)", TypeList, "[]"},
+		{`list_index_of([1, "a", 2.0], 2)`, TypeInt, "2"},
+		{"list_index_of([1], 5)", TypeInt, "-1"},
+		{"contains([1, [2]], [2])", TypeBool, "true"},
+		{"contains([1, 2], 3)", TypeBool, "false"},
+		{"list_pop([1, 2, 3])", TypeInt, "3"},
+		{"list_pop([1, 2, 3], 0)", TypeInt, "1"},
+	})
+	checkExprErrors(t, []errorCase{
+		{"list_pop([])", KindIndex},
+		{"list_pop([1], 1)", KindIndex},
+		{"list_slice([1], 0, 2)", KindIndex},
+		{"list_slice([1, 2], 2, 1)", KindIndex},
+		{`list_sort([1, "a"])`, KindType},
+		{"list_remove([1], 2)", KindValue},
+		{"list_insert([1], 3, 0)", KindIndex},
+		{"len(5)", KindType},
+	})
+}
+
+func TestListMutation(t *testing.T) {
+	checkPrograms(t, []programCase{
+		{"xs = [5, 3]\nlist_insert (xs, 1, 7)\nlist_insert (xs, 3, 9)\nprint (xs)", "[5, 7, 3, 9]\n"},
+		{"xs = [1, 2, 1]\nlist_remove (xs, 1)\nprint (xs)", "[2, 1]\n"},
+		{"xs = [1, 2, 3]\nx = list_pop(xs)\nprint (x)\nprint (xs)", "3\n[1, 2]\n"},
+		{"xs = [1, 2, 3]\nlist_reverse (xs)\nprint (xs)", "[3, 2, 1]\n"},
+		{"xs = [3, 1.5, 2, 0.5d]\nlist_sort (xs)\nprint (xs)", "[0.5, 1.5, 2, 3]\n"},
+		{"xs = [\"груша\", \"абрикос\", \"яблоко\"]\nlist_sort (xs)\nprint (xs)", "[\"абрикос\", \"груша\", \"яблоко\"]\n"},
+		// Конкатенация создаёт новый список
+		{"a = [1]\nb = a + [2]\nlist_append (b, 3)\nprint (a)\nprint (b)", "[1]\n[1, 2, 3]\n"},
+		// Индексы — выражения и переменные
+		{"xs = [10, 20, 30]\ni = 1\nprint (list_get(xs, i + 1))\nprint (array_get(xs, len(xs) - 3))", "30\n10\n"},
+	})
+}
+
+func TestListSortLeavesListOnError(t *testing.T) {
+	list := NewList(Int(3), Str("a"), Int(1))
+	if err := listSort(list); err == nil || list.String() != `[3, "a", 1]` {
+		t.Errorf("после ошибки список %v, ожидалось без изменений", list)
+	}
+}
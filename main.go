@@ -1,29 +1,54 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Использование: clashlang <имя_файла.clash>")
-		return
+	maxDepth := flag.Int("max-depth", DefaultMaxDepth, fmt.Sprintf("допустимая глубина вложенных вызовов функций (не больше %d)", MaxDepthLimit))
+	strict := flag.Bool("strict", false, "останавливаться на первой нераспознанной команде")
+	flag.Usage = func() {
+		fmt.Println("Использование: clashlang [--max-depth N] [--strict] <имя_файла.clash>")
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	filename := os.Args[1]
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	filename := flag.Arg(0)
 	if !strings.HasSuffix(filename, ".clash") {
 		fmt.Println("Ошибка: файл должен иметь расширение .clash")
-		return
+		os.Exit(1)
 	}
 
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Printf("Ошибка при чтении файла: %v\n", err)
-		return
+		os.Exit(1)
 	}
 
-	interpreter := NewInterpreter()
-	interpreter.ExecuteProgram(string(content))
+	interpreter, err := NewInterpreter()
+	if err != nil {
+		fmt.Println("Ошибка:", err)
+		os.Exit(1)
+	}
+	if err := interpreter.SetMaxDepth(*maxDepth); err != nil {
+		fmt.Println("Ошибка:", err)
+		os.Exit(1)
+	}
+	interpreter.SetStrict(*strict)
+	if err := interpreter.ExecuteProgram(filename, string(content)); err != nil {
+		if ce, ok := err.(*ClashError); ok {
+			fmt.Fprintln(os.Stderr, ce.Diagnostic())
+		} else {
+			fmt.Fprintln(os.Stderr, "Ошибка:", err)
+		}
+		os.Exit(1)
+	}
 }
\ No newline at end of file
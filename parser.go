@@ -0,0 +1,1086 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patternElem — элемент скомпилированного шаблона команды:
+// либо литеральная лексема, либо параметр {{имя}}
+type patternElem struct {
+	Literal Token
+	Param   string
+}
+
+// compilePattern разбивает шаблон из commands.json на лексемы и параметры
+func compilePattern(pattern string) ([]patternElem, error) {
+	var elems []patternElem
+	rest := pattern
+	for rest != "" {
+		open := strings.Index(rest, "{{")
+		literal := rest
+		if open != -1 {
+			literal = rest[:open]
+		}
+		tokens, err := Tokenize(literal)
+		if err != nil {
+			return nil, err
+		}
+		for _, tok := range tokens {
+			if tok.Kind == TokEOF || tok.Kind == TokNewline {
+				continue
+			}
+			elems = append(elems, patternElem{Literal: tok})
+		}
+		if open == -1 {
+			break
+		}
+		end := strings.Index(rest[open:], "}}")
+		if end == -1 {
+			return nil, fmt.Errorf("незакрытый параметр в шаблоне %q", pattern)
+		}
+		elems = append(elems, patternElem{Param: rest[open+2 : open+end]})
+		rest = rest[open+end+2:]
+	}
+	return elems, nil
+}
+
+// matchPattern сопоставляет строку программы с шаблоном.
+// Каждый параметр захватывает непустую последовательность лексем.
+func matchPattern(elems []patternElem, line []Token) (map[string][]Token, bool) {
+	params := make(map[string][]Token)
+	if matchFrom(elems, 0, line, 0, params) {
+		return params, true
+	}
+	return nil, false
+}
+
+func matchFrom(elems []patternElem, ei int, line []Token, ti int, params map[string][]Token) bool {
+	if ei == len(elems) {
+		return ti == len(line)
+	}
+	el := elems[ei]
+	if el.Param == "" {
+		if ti < len(line) && sameToken(el.Literal, line[ti]) {
+			return matchFrom(elems, ei+1, line, ti+1, params)
+		}
+		return false
+	}
+	for end := ti + 1; end <= len(line); end++ {
+		if !balanced(line[ti:end]) {
+			continue
+		}
+		if matchFrom(elems, ei+1, line, end, params) {
+			params[el.Param] = line[ti:end]
+			return true
+		}
+	}
+	return false
+}
+
+func sameToken(a, b Token) bool {
+	return a.Kind == b.Kind && strings.EqualFold(a.Text, b.Text)
+}
+
+// balanced проверяет, что скобки в последовательности лексем сбалансированы:
+// параметр не может захватить только открывающую или закрывающую скобку
+func balanced(span []Token) bool {
+	var stack []string
+	for _, tok := range span {
+		if tok.Kind != TokPunct {
+			continue
+		}
+		switch tok.Text {
+		case "(", "[", "{":
+			stack = append(stack, tok.Text)
+		case ")", "]", "}":
+			if len(stack) == 0 || stack[len(stack)-1] != openingBracket[tok.Text] {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return len(stack) == 0
+}
+
+var openingBracket = map[string]string{")": "(", "]": "[", "}": "{"}
+
+// Parser строит синтаксическое дерево программы
+type Parser struct {
+	src   string
+	lines [][]Token
+	line  int
+	table *DispatchTable
+
+	strict   bool          // нераспознанная строка — ошибка разбора, а не предупреждение
+	warnings []*ClashError // предупреждения о нераспознанных строках
+	mismatch error         // почему совпавший по шаблону параметр не разобрался
+
+	funcDepth int // глубина вложенности тел функций: return допустим только внутри
+	loopDepth int // глубина вложенности циклов в текущей функции: для break/continue
+
+	// Состояние разбора выражения внутри одной строки
+	tokens []Token
+	pos    int
+}
+
+func NewParser(src string, table *DispatchTable) (*Parser, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &Parser{src: src, table: table}
+	var current []Token
+	for _, tok := range tokens {
+		if tok.Kind == TokNewline || tok.Kind == TokEOF {
+			if len(current) > 0 {
+				p.lines = append(p.lines, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, tok)
+	}
+	return p, nil
+}
+
+// ParseProgram разбирает весь текст программы
+func (p *Parser) ParseProgram() (*Program, error) {
+	stmts, _, err := p.parseBody(nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{Stmts: stmts, Warnings: p.warnings}, nil
+}
+
+// parseBody читает операторы до строки, на которой end возвращает true
+// (сама эта строка поглощается и возвращается; nil — файл закончился раньше).
+// labels разрешает метки case/default — только непосредственно внутри switch.
+func (p *Parser) parseBody(end func([]Token) bool, labels bool) ([]Stmt, []Token, error) {
+	var body []Stmt
+	for p.line < len(p.lines) {
+		line := p.lines[p.line]
+		p.line++
+		if end != nil && end(line) {
+			return body, line, nil
+		}
+		stmt, err := p.parseStatement(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch stmt.(type) {
+		case nil:
+			continue
+		case *CaseStmt, *DefaultStmt:
+			if !labels {
+				return nil, nil, syntaxError(line[0].Pos, "метка вне switch")
+			}
+		case *DoWhileStmt:
+			return nil, nil, syntaxError(line[0].Pos, "while без блока do")
+		case *ElseStmt, *ElseIfStmt:
+			return nil, nil, syntaxError(line[0].Pos, "else без if")
+		case *ReturnStmt:
+			if p.funcDepth == 0 {
+				return nil, nil, syntaxError(line[0].Pos, "return вне функции")
+			}
+		case *BreakStmt, *ContinueStmt:
+			if p.loopDepth == 0 {
+				return nil, nil, syntaxError(line[0].Pos, "%s вне цикла", strings.ToLower(line[0].Text))
+			}
+		}
+		body = append(body, stmt)
+	}
+	return body, nil, nil
+}
+
+func isLine(text string) func([]Token) bool {
+	return func(line []Token) bool {
+		return len(line) == 1 && line[0].Text == text
+	}
+}
+
+// closesIf — строка, завершающая ветку if: "}", "} else {" или "} else if ... {"
+func closesIf(line []Token) bool {
+	return isLine("}")(line) || lineIs(line, "}", "else")
+}
+
+// parseStatement разбирает одну строку вместе с вложенным блоком, если строка его открывает.
+// Нераспознанная строка пропускается с предупреждением, а в строгом режиме — ошибка.
+func (p *Parser) parseStatement(line []Token) (Stmt, error) {
+	pos := line[0].Pos
+	switch {
+	case lineIs(line, "function", "("):
+		name, err := p.parseNameList(line[2:])
+		if err != nil || len(name) != 1 {
+			return nil, p.skipUnknown(line)
+		}
+		restore := p.enterFunction()
+		body, _, err := p.parseBody(isLine(")"), false)
+		restore()
+		if err != nil {
+			return nil, err
+		}
+		return &FunctionDecl{stmtBase: stmtBase{pos}, Name: name[0], Body: body}, nil
+	case lineIs(line, "memory", "start", "("):
+		return nil, nil
+	case lineIs(line, "memory", "load", "("):
+		names, err := p.parseNameList(line[3:])
+		if err != nil {
+			return nil, p.skipUnknown(line)
+		}
+		return &MemoryLoadStmt{stmtBase: stmtBase{pos}, Names: names}, nil
+	case isLine("}")(line):
+		return nil, syntaxError(pos, "лишняя }")
+	case isLine(")")(line):
+		return nil, nil
+	}
+
+	if eq := assignIndex(line); eq > 0 {
+		return p.parseAssign(line, eq)
+	}
+	stmt, err := p.matchCommand(line, pos)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return nil, p.skipUnknown(line)
+	}
+	if err := p.parseBlock(stmt); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// assignIndex возвращает позицию "=" в присваивании "имя = ..." или
+// "имя[индекс]... = ...", а для остальных строк — 0
+func assignIndex(line []Token) int {
+	if len(line) < 2 || line[0].Kind != TokIdent {
+		return 0
+	}
+	depth := 0
+	for idx := 1; idx < len(line); idx++ {
+		tok := line[idx]
+		if tok.Kind != TokPunct {
+			if depth == 0 {
+				return 0
+			}
+			continue
+		}
+		switch tok.Text {
+		case "[", "(", "{":
+			if depth == 0 && tok.Text != "[" {
+				return 0
+			}
+			depth++
+		case "]", ")", "}":
+			depth--
+		case "=":
+			if depth == 0 {
+				return idx
+			}
+		default:
+			if depth == 0 {
+				return 0
+			}
+		}
+	}
+	return 0
+}
+
+// parseAssign разбирает присваивание: имя = выражение или имя[индекс] = выражение
+func (p *Parser) parseAssign(line []Token, eq int) (Stmt, error) {
+	if eq == len(line)-1 {
+		return nil, syntaxError(line[eq].Pos, "после = ожидается выражение")
+	}
+	target, err := p.parseExprSpan(line[:eq])
+	if err != nil {
+		return nil, syntaxError(line[0].Pos, "%v", err)
+	}
+	value, err := p.parseExprSpan(line[eq+1:])
+	if err != nil {
+		return nil, syntaxError(line[eq+1].Pos, "%v", err)
+	}
+	return &AssignStmt{stmtBase: stmtBase{line[0].Pos}, Target: target, Value: value}, nil
+}
+
+// skipUnknown обрабатывает нераспознанную строку: в строгом режиме возвращает
+// ошибку, иначе запоминает предупреждение и строка пропускается вместе с
+// блоком, который она открывает
+func (p *Parser) skipUnknown(line []Token) error {
+	err := p.unknownCommand(line)
+	p.mismatch = nil
+	if p.strict {
+		return err
+	}
+	p.warnings = append(p.warnings, err)
+	if line[len(line)-1].Text == "{" {
+		_, _, err := p.parseBody(isLine("}"), false)
+		return err
+	}
+	return nil
+}
+
+// matchCommand перебирает команды от самого специфичного шаблона к общему
+// Ошибка с позицией (*ClashError) означает, что команда узнана, но записана неверно.
+func (p *Parser) matchCommand(line []Token, pos Pos) (Stmt, error) {
+	p.mismatch = nil
+	for _, entry := range p.table.Entries() {
+		params, ok := matchPattern(entry.Elems, line)
+		if !ok {
+			continue
+		}
+		stmt, err := p.buildStatement(entry, params, pos)
+		if ce, ok := err.(*ClashError); ok {
+			return nil, ce
+		}
+		// Совпал шаблон, но параметры не разбираются — пробуем другие команды
+		if err == nil {
+			p.mismatch = nil
+			return stmt, nil
+		}
+		if p.mismatch == nil {
+			p.mismatch = err
+		}
+	}
+	return nil, nil
+}
+
+// parseBlock читает тело оператора, открывающего блок, до парной }
+func (p *Parser) parseBlock(stmt Stmt) error {
+	var body *[]Stmt
+	switch s := stmt.(type) {
+	case *IfStmt:
+		return p.parseIfBody(s)
+	case *ForStmt:
+		body = &s.Body
+		p.loopDepth++
+		defer func() { p.loopDepth-- }()
+	case *WhileStmt:
+		body = &s.Body
+		p.loopDepth++
+		defer func() { p.loopDepth-- }()
+	case *DoStmt:
+		body = &s.Body
+		p.loopDepth++
+		defer func() { p.loopDepth-- }()
+	case *DefFuncStmt:
+		body = &s.Body
+		defer p.enterFunction()()
+	case *SwitchStmt:
+		return p.parseSwitchBody(s)
+	default:
+		return nil
+	}
+
+	stmts, closing, err := p.parseBody(isLine("}"), false)
+	if err != nil {
+		return err
+	}
+	if closing == nil {
+		return syntaxError(stmt.Position(), "блок не закрыт символом }")
+	}
+	*body = stmts
+
+	if do, ok := stmt.(*DoStmt); ok {
+		return p.parseDoWhile(do)
+	}
+	return nil
+}
+
+// enterFunction начинает разбор тела функции: break/continue внутри него не
+// относятся к циклам снаружи. Возвращает функцию восстановления состояния.
+func (p *Parser) enterFunction() func() {
+	savedLoops := p.loopDepth
+	p.funcDepth++
+	p.loopDepth = 0
+	return func() {
+		p.funcDepth--
+		p.loopDepth = savedLoops
+	}
+}
+
+// parseIfBody читает ветки if вместе с цепочкой "} else if ... {" и "} else {"
+func (p *Parser) parseIfBody(s *IfStmt) error {
+	stmts, closing, err := p.parseBody(closesIf, false)
+	if err != nil {
+		return err
+	}
+	if closing == nil {
+		return syntaxError(s.Position(), "блок не закрыт символом }")
+	}
+	s.Body = stmts
+	if isLine("}")(closing) {
+		return nil
+	}
+
+	branch, err := p.matchCommand(closing, closing[0].Pos)
+	if err != nil {
+		return err
+	}
+	switch branch := branch.(type) {
+	case *ElseIfStmt:
+		// else if превращается во вложенный if внутри ветки else
+		nested := &IfStmt{stmtBase: branch.stmtBase, Cond: branch.Cond}
+		s.Else = []Stmt{nested}
+		return p.parseIfBody(nested)
+	case *ElseStmt:
+		stmts, closing, err := p.parseBody(isLine("}"), false)
+		if err != nil {
+			return err
+		}
+		if closing == nil {
+			return syntaxError(branch.Position(), "блок else не закрыт символом }")
+		}
+		s.Else = stmts
+		return nil
+	}
+	return syntaxError(closing[0].Pos, "неверная ветка else")
+}
+
+// parseDoWhile читает строку с условием, завершающую do { ... }
+func (p *Parser) parseDoWhile(do *DoStmt) error {
+	if p.line < len(p.lines) {
+		line := p.lines[p.line]
+		stmt, err := p.matchCommand(line, line[0].Pos)
+		if err != nil {
+			return err
+		}
+		if cond, ok := stmt.(*DoWhileStmt); ok {
+			p.line++
+			do.While = cond
+			return nil
+		}
+	}
+	return syntaxError(do.Position(), "после блока do ожидалось while")
+}
+
+// parseSwitchBody раскладывает тело switch по меткам case/default
+func (p *Parser) parseSwitchBody(sw *SwitchStmt) error {
+	stmts, closing, err := p.parseBody(isLine("}"), true)
+	if err != nil {
+		return err
+	}
+	if closing == nil {
+		return syntaxError(sw.Position(), "блок не закрыт символом }")
+	}
+	var current *[]Stmt
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *CaseStmt:
+			sw.Cases = append(sw.Cases, s)
+			current = &s.Body
+		case *DefaultStmt:
+			if sw.Default != nil {
+				return syntaxError(s.Position(), "повторная метка default")
+			}
+			sw.Default = s
+			current = &s.Body
+		default:
+			if current == nil {
+				return syntaxError(stmt.Position(), "оператор до первой метки case")
+			}
+			*current = append(*current, stmt)
+		}
+	}
+	return nil
+}
+
+// parseNameList разбирает "a, b, c)" — список имён до закрывающей скобки
+func (p *Parser) parseNameList(toks []Token) ([]string, error) {
+	if len(toks) == 0 || toks[len(toks)-1].Text != ")" {
+		return nil, fmt.Errorf("ожидалась \")\"")
+	}
+	var names []string
+	expectName := true
+	for _, tok := range toks[:len(toks)-1] {
+		if expectName && tok.Kind == TokIdent {
+			names = append(names, tok.Text)
+		} else if !expectName && tok.Text == "," {
+		} else {
+			return nil, fmt.Errorf("неожиданная лексема %s", tok)
+		}
+		expectName = !expectName
+	}
+	if expectName {
+		return nil, fmt.Errorf("ожидалось имя")
+	}
+	return names, nil
+}
+
+// lineIs проверяет, что строка начинается с указанных лексем (без учёта регистра)
+func lineIs(line []Token, words ...string) bool {
+	if len(line) < len(words) {
+		return false
+	}
+	for idx, w := range words {
+		if !strings.EqualFold(line[idx].Text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildStatement превращает совпавшую команду в типизированный узел
+func (p *Parser) buildStatement(entry dispatchEntry, params map[string][]Token, pos Pos) (Stmt, error) {
+	cmd := entry.Cmd
+	base := stmtBase{pos}
+	var err error
+	name := func(param string) string {
+		n, e := identSpan(params[param])
+		if e != nil && err == nil {
+			err = e
+		}
+		return n
+	}
+	expr := func(param string) Expr {
+		x, e := p.parseExprSpan(params[param])
+		if e != nil && err == nil {
+			err = e
+		}
+		return x
+	}
+
+	var stmt Stmt
+	switch cmd.ID {
+	case 1:
+		stmt = &PrintStmt{stmtBase: base, Value: expr("var")}
+	case 2:
+		stmt = &InputStmt{stmtBase: base, Name: name("var"), Mode: InputNumber}
+	case 3:
+		stmt = &SolveStmt{stmtBase: base, Expr: expr("expr")}
+	case 4, 7:
+		stmt = &ResultOutStmt{stmtBase: base, Name: name("var")}
+	case 5:
+		stmt = &InputStmt{stmtBase: base, Name: name("var"), Mode: InputText}
+	case 6:
+		var parts []Expr
+		parts, err = p.parseTextParts(params["expr"])
+		stmt = &TextStmt{stmtBase: base, Parts: parts}
+	case 8:
+		stmt = &IfStmt{stmtBase: base, Cond: expr("cond")}
+	case 9, 57:
+		var call *CallExpr
+		call, err = p.parseCallTarget(params["func"])
+		if call != nil {
+			stmt = &JumpStmt{stmtBase: base, Name: call.Name, Args: call.Args}
+		}
+	case 10:
+		stmt = &MemoryOutStmt{stmtBase: base}
+	case 33:
+		stmt = &ForStmt{stmtBase: base, Var: name("var"), Start: expr("start"), End: expr("end")}
+	case 34:
+		stmt = &WhileStmt{stmtBase: base, Cond: expr("cond")}
+	case 35:
+		stmt = &DoStmt{stmtBase: base}
+	case 36:
+		stmt = &DoWhileStmt{stmtBase: base, Cond: expr("cond")}
+	case 37:
+		stmt = &SwitchStmt{stmtBase: base, Var: name("var")}
+	case 38:
+		stmt = &CaseStmt{stmtBase: base, Value: expr("value")}
+	case 39:
+		stmt = &DefaultStmt{stmtBase: base}
+	case 60:
+		var call *CallExpr
+		call, err = p.parseSignature(params["signature"])
+		if call != nil {
+			def := &DefFuncStmt{stmtBase: base, Name: call.Name}
+			for _, arg := range call.Args {
+				def.Params = append(def.Params, arg.(*Ident).Name)
+			}
+			stmt = def
+		}
+	case 61:
+		stmt = &ReturnStmt{stmtBase: base, Value: expr("value")}
+	case 62:
+		stmt = &ReturnStmt{stmtBase: base}
+	case 63:
+		stmt = &BreakStmt{stmtBase: base}
+	case 64:
+		stmt = &ContinueStmt{stmtBase: base}
+	case 58:
+		stmt = &ElseStmt{stmtBase: base}
+	case 59:
+		stmt = &ElseIfStmt{stmtBase: base, Cond: expr("cond")}
+	case 42:
+		var value Expr
+		if span := params["var"]; len(span) == 1 && span[0].Kind == TokString {
+			if value, err = p.parseTemplate(span[0]); err != nil {
+				return nil, err
+			}
+		} else {
+			value = expr("var")
+		}
+		stmt = &PrintStmt{stmtBase: base, Value: value, Formatted: true}
+	case 43:
+		stmt = &InputStmt{stmtBase: base, Name: name("var"), Mode: InputValue}
+	case 56:
+		stmt = &DefStmt{stmtBase: base, Name: name("name")}
+	case 73:
+		stmt = &SolveStmt{stmtBase: base, Expr: &ExistsExpr{exprBase: exprBase{pos}, Name: name("var")}}
+	default:
+		// Остальные команды — встроенные функции с аргументами в порядке шаблона
+		builtin := &BuiltinStmt{stmtBase: base, Cmd: cmd}
+		for _, el := range entry.Elems {
+			if el.Param == "" {
+				continue
+			}
+			if bareParams[cmd.ID] == el.Param {
+				builtin.Args = append(builtin.Args, p.bareOperand(params[el.Param]))
+			} else {
+				builtin.Args = append(builtin.Args, expr(el.Param))
+			}
+		}
+		stmt = builtin
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// bareParams — параметры команд, которые можно писать без кавычек:
+// env (HOME), file.read (data/notes.txt), decimal.rounding (down)
+var bareParams = map[int]string{
+	40: "file", // file.read
+	41: "file", // file.write
+	55: "var",  // env
+	72: "mode", // decimal.rounding
+}
+
+// bareOperand разбирает параметр без кавычек. Выражение вычисляется, как в
+// форме вызова, но если в нём есть неопределённое имя или оно не разбирается,
+// берётся исходный текст всего параметра.
+func (p *Parser) bareOperand(span []Token) *BareExpr {
+	first, last := span[0], span[len(span)-1]
+	bare := &BareExpr{
+		exprBase: exprBase{first.Pos},
+		Raw:      p.src[first.Offset : last.Offset+len(last.Text)],
+	}
+	if e, err := p.parseExprSpan(span); err == nil {
+		bare.Expr = e
+		bare.Names = exprNames(e, nil)
+	}
+	return bare
+}
+
+// exprNames собирает имена переменных, на которые ссылается выражение
+func exprNames(e Expr, names []string) []string {
+	switch n := e.(type) {
+	case *Ident:
+		names = append(names, n.Name)
+	case *UnaryExpr:
+		names = exprNames(n.Operand, names)
+	case *BinaryExpr:
+		names = exprNames(n.Right, exprNames(n.Left, names))
+	case *IndexExpr:
+		names = exprNames(n.Index, exprNames(n.Target, names))
+	case *ListLit:
+		for _, item := range n.Items {
+			names = exprNames(item, names)
+		}
+	case *DictLit:
+		for idx, key := range n.Keys {
+			names = exprNames(n.Values[idx], exprNames(key, names))
+		}
+	case *CallExpr:
+		for _, arg := range n.Args {
+			names = exprNames(arg, names)
+		}
+	case *TemplateLit:
+		for _, part := range n.Parts {
+			if part.Expr != nil {
+				names = exprNames(part.Expr, names)
+			}
+		}
+	}
+	return names
+}
+
+// parseTextParts разбирает аргумент text: имена и строки, разделённые "+"
+func (p *Parser) parseTextParts(span []Token) ([]Expr, error) {
+	var parts []Expr
+	for idx, tok := range span {
+		if idx%2 == 1 {
+			if tok.Text != "+" {
+				return nil, fmt.Errorf("ожидался \"+\", получено %s", tok)
+			}
+			continue
+		}
+		switch tok.Kind {
+		case TokIdent:
+			parts = append(parts, &Ident{exprBase: exprBase{tok.Pos}, Name: tok.Text})
+		case TokString:
+			parts = append(parts, &StringLit{exprBase: exprBase{tok.Pos}, Value: tok.Value})
+		default:
+			return nil, fmt.Errorf("ожидалось имя переменной или строка, получено %s", tok)
+		}
+	}
+	if len(span)%2 == 0 {
+		return nil, fmt.Errorf("ожидалось имя переменной")
+	}
+	return parts, nil
+}
+
+// parseCallTarget разбирает цель jump/function_call: имя или вызов с аргументами
+func (p *Parser) parseCallTarget(span []Token) (*CallExpr, error) {
+	if name, err := identSpan(span); err == nil {
+		return &CallExpr{exprBase: exprBase{span[0].Pos}, Name: name}, nil
+	}
+	e, err := p.parseExprSpan(span)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := e.(*CallExpr)
+	if !ok {
+		return nil, fmt.Errorf("ожидался вызов функции")
+	}
+	return call, nil
+}
+
+// parseSignature разбирает заголовок def: имя(a, b)
+func (p *Parser) parseSignature(span []Token) (*CallExpr, error) {
+	e, err := p.parseExprSpan(span)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := e.(*CallExpr)
+	if !ok {
+		return nil, fmt.Errorf("ожидался заголовок функции имя(параметры)")
+	}
+	seen := make(map[string]bool)
+	for _, arg := range call.Args {
+		ident, ok := arg.(*Ident)
+		if !ok {
+			return nil, fmt.Errorf("параметр функции %s должен быть именем", call.Name)
+		}
+		if seen[ident.Name] {
+			return nil, fmt.Errorf("повторный параметр %s функции %s", ident.Name, call.Name)
+		}
+		seen[ident.Name] = true
+	}
+	return call, nil
+}
+
+func identSpan(span []Token) (string, error) {
+	if len(span) != 1 || span[0].Kind != TokIdent {
+		return "", fmt.Errorf("ожидалось имя")
+	}
+	return span[0].Text, nil
+}
+
+// parseExprSpan разбирает последовательность лексем как одно выражение целиком
+func (p *Parser) parseExprSpan(span []Token) (Expr, error) {
+	sub := &Parser{src: p.src, tokens: span}
+	e, err := sub.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if sub.pos < len(sub.tokens) {
+		return nil, fmt.Errorf("лишняя лексема %s", sub.tokens[sub.pos])
+	}
+	return e, nil
+}
+
+func (p *Parser) peek() (Token, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return Token{}, false
+}
+
+// binaryPrecedence — приоритеты бинарных операторов (больше — связывает сильнее).
+// В условиях "=" означает сравнение, как и "==".
+var binaryPrecedence = map[string]int{
+	"or":  1,
+	"and": 2,
+	"=":   4,
+	"==":  4,
+	"!=":  4,
+	"<":   4,
+	">":   4,
+	"<=":  4,
+	">=":  4,
+	"+":   5,
+	"-":   5,
+	"*":   6,
+	"/":   6,
+	"%":   6,
+	"div": 6,
+}
+
+// notPrecedence — приоритет унарного not: ниже сравнений, выше and
+const notPrecedence = 3
+
+// binaryOp возвращает оператор, которым является лексема, и его приоритет
+func binaryOp(tok Token) (string, int, bool) {
+	op := tok.Text
+	if tok.Kind == TokIdent {
+		op = strings.ToLower(op)
+	} else if tok.Kind != TokPunct {
+		return "", 0, false
+	}
+	prec, ok := binaryPrecedence[op]
+	return op, prec, ok
+}
+
+// parseExpr разбирает инфиксное выражение с учётом приоритетов операторов
+func (p *Parser) parseExpr() (Expr, error) {
+	return p.parseBinary(1)
+}
+
+// parseBinary — разбор методом подъёма приоритетов; все операторы левоассоциативны
+func (p *Parser) parseBinary(minPrec int) (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok {
+			return left, nil
+		}
+		op, prec, isOp := binaryOp(tok)
+		if !isOp || prec < minPrec {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{exprBase: exprBase{tok.Pos}, Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *Parser) parseUnary() (Expr, error) {
+	if tok, ok := p.peek(); ok && tok.Kind == TokIdent && strings.EqualFold(tok.Text, "not") {
+		p.pos++
+		operand, err := p.parseBinary(notPrecedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{exprBase: exprBase{tok.Pos}, Op: "not", Operand: operand}, nil
+	}
+	if tok, ok := p.peek(); ok && tok.Kind == TokPunct && tok.Text == "-" {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{exprBase: exprBase{tok.Pos}, Op: "-", Operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix разбирает значение с индексами: xs[0], m["a"][2]
+func (p *Parser) parsePostfix() (Expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.Kind != TokPunct || tok.Text != "[" {
+			return e, nil
+		}
+		p.pos++
+		index, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		e = &IndexExpr{exprBase: exprBase{tok.Pos}, Target: e, Index: index}
+	}
+}
+
+// parseList разбирает элементы литерала списка после "["
+func (p *Parser) parseList(open Token) (Expr, error) {
+	list := &ListLit{exprBase: exprBase{open.Pos}}
+	for !p.closing("]") {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+		if !p.separator("]") {
+			return nil, fmt.Errorf("в списке ожидалось \",\" или \"]\"")
+		}
+	}
+	return list, nil
+}
+
+// parseDict разбирает пары литерала словаря после "{"
+func (p *Parser) parseDict(open Token) (Expr, error) {
+	dict := &DictLit{exprBase: exprBase{open.Pos}}
+	for !p.closing("}") {
+		key, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		dict.Keys = append(dict.Keys, key)
+		dict.Values = append(dict.Values, value)
+		if !p.separator("}") {
+			return nil, fmt.Errorf("в словаре ожидалось \",\" или \"}\"")
+		}
+	}
+	return dict, nil
+}
+
+// closing пропускает закрывающую скобку литерала, если она следующая
+func (p *Parser) closing(text string) bool {
+	if tok, ok := p.peek(); ok && tok.Kind == TokPunct && tok.Text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// separator пропускает запятую между элементами; false — нет ни запятой,
+// ни закрывающей скобки. Скобка остаётся для closing.
+func (p *Parser) separator(close string) bool {
+	tok, ok := p.peek()
+	if !ok || tok.Kind != TokPunct {
+		return false
+	}
+	if tok.Text == "," {
+		p.pos++
+		return true
+	}
+	return tok.Text == close
+}
+
+// expect пропускает ожидаемую лексему-символ
+func (p *Parser) expect(text string) error {
+	tok, ok := p.peek()
+	if !ok {
+		return fmt.Errorf("ожидалось %q", text)
+	}
+	if tok.Kind != TokPunct || tok.Text != text {
+		return fmt.Errorf("ожидалось %q, получено %s", text, tok)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *Parser) parsePrimary() (Expr, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("ожидалось выражение")
+	}
+	p.pos++
+	switch tok.Kind {
+	case TokInt:
+		n, ok := parseInteger(tok.Text)
+		if !ok {
+			return nil, fmt.Errorf("неверное число %s", tok.Text)
+		}
+		return &NumberLit{exprBase: exprBase{tok.Pos}, Value: n}, nil
+	case TokFloat:
+		f, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &NumberLit{exprBase: exprBase{tok.Pos}, Value: Float(f)}, nil
+	case TokDecimal:
+		d, ok := parseDecimal(strings.TrimSuffix(tok.Text, "d"))
+		if !ok {
+			return nil, fmt.Errorf("неверное число %s", tok.Text)
+		}
+		return &NumberLit{exprBase: exprBase{tok.Pos}, Value: d}, nil
+	case TokString:
+		return &StringLit{exprBase: exprBase{tok.Pos}, Value: tok.Value}, nil
+	case TokIdent:
+		if strings.EqualFold(tok.Text, "nil") {
+			return &NilLit{exprBase: exprBase{tok.Pos}}, nil
+		}
+		if strings.EqualFold(tok.Text, "true") || strings.EqualFold(tok.Text, "false") {
+			return &BoolLit{exprBase: exprBase{tok.Pos}, Value: strings.EqualFold(tok.Text, "true")}, nil
+		}
+		if name, ok := p.callName(tok); ok {
+			return p.parseCall(name)
+		}
+		return &Ident{exprBase: exprBase{tok.Pos}, Name: tok.Text}, nil
+	case TokPunct:
+		switch tok.Text {
+		case "(":
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			return p.parseList(tok)
+		case "{":
+			return p.parseDict(tok)
+		}
+	}
+	return nil, fmt.Errorf("неожиданная лексема %s", tok)
+}
+
+// callName распознаёт начало вызова: имя или имя через точки (text.upper),
+// за которым идёт "(". Лексемы вызова вместе со скобкой пропускаются.
+func (p *Parser) callName(first Token) (Token, bool) {
+	name := first
+	pos := p.pos
+	for pos+1 < len(p.tokens) && p.tokens[pos].Text == "." && p.tokens[pos+1].Kind == TokIdent {
+		name.Text += "." + p.tokens[pos+1].Text
+		pos += 2
+	}
+	if pos < len(p.tokens) && p.tokens[pos].Kind == TokPunct && p.tokens[pos].Text == "(" {
+		p.pos = pos + 1
+		return name, true
+	}
+	return first, false
+}
+
+// parseCall разбирает аргументы вызова после открывающей скобки
+func (p *Parser) parseCall(name Token) (Expr, error) {
+	call := &CallExpr{exprBase: exprBase{name.Pos}, Name: name.Text}
+	if tok, ok := p.peek(); ok && tok.Kind == TokPunct && tok.Text == ")" {
+		p.pos++
+		return call, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if tok, ok := p.peek(); ok && tok.Kind == TokPunct && tok.Text == "," {
+			p.pos++
+			continue
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(call.Name, "exists") {
+			return existsCall(call)
+		}
+		return call, nil
+	}
+}
+
+// existsCall превращает exists(x) в проверку имени: аргумент не вычисляется,
+// иначе обращение к неопределённой переменной было бы ошибкой
+func existsCall(call *CallExpr) (Expr, error) {
+	if len(call.Args) == 1 {
+		if ident, ok := call.Args[0].(*Ident); ok {
+			return &ExistsExpr{exprBase: call.exprBase, Name: ident.Name}, nil
+		}
+	}
+	return nil, fmt.Errorf("exists ожидает одно имя переменной")
+}
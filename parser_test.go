@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lineTokens разбивает одну строку на лексемы без завершающего EOF
+func lineTokens(t *testing.T, src string) []Token {
+	t.Helper()
+	tokens, err := Tokenize(src)
+	if err != nil {
+		t.Fatalf("Tokenize(%q): %v", src, err)
+	}
+	var line []Token
+	for _, tok := range tokens {
+		if tok.Kind != TokEOF && tok.Kind != TokNewline {
+			line = append(line, tok)
+		}
+	}
+	return line
+}
+
+// spanText склеивает текст лексем параметра через пробел
+func spanText(span []Token) string {
+	parts := make([]string, len(span))
+	for idx, tok := range span {
+		parts[idx] = tok.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		line    string
+		params  map[string]string // nil — строка не подходит
+	}{
+		{"pow ({{base}}, {{exponent}})", "pow (a, b)", map[string]string{"base": "a", "exponent": "b"}},
+		{"pow ({{base}}, {{exponent}})", "pow(  a ,b )", map[string]string{"base": "a", "exponent": "b"}},
+		{"pow ({{base}}, {{exponent}})", "pow (f(1, 2), b)", map[string]string{"base": "f ( 1 , 2 )", "exponent": "b"}},
+		{"print ({{var}})", "PRINT (x)", map[string]string{"var": "x"}},
+		{"print ({{var}})", "print ()", nil},
+		{"print ({{var}})", "print (x", nil},
+		{"solve.out = {{var}}", "solve.out = result", map[string]string{"var": "result"}},
+		{"for {{var}} from {{start}} to {{end}} {", "for i from 1 to n + 1 {", map[string]string{"var": "i", "start": "1", "end": "n + 1"}},
+		{"memory out", "memory out", map[string]string{}},
+		{"memory out", "memory out now", nil},
+	}
+	for _, tt := range tests {
+		elems, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q): %v", tt.pattern, err)
+		}
+		params, ok := matchPattern(elems, lineTokens(t, tt.line))
+		if tt.params == nil {
+			if ok {
+				t.Errorf("%q подошла под %q, а не должна", tt.line, tt.pattern)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q не подошла под %q", tt.line, tt.pattern)
+			continue
+		}
+		got := make(map[string]string)
+		for name, span := range params {
+			got[name] = spanText(span)
+		}
+		if !reflect.DeepEqual(got, tt.params) {
+			t.Errorf("%q по %q: параметры %v, ожидалось %v", tt.line, tt.pattern, got, tt.params)
+		}
+	}
+}
+
+func TestCompilePatternUnclosedParam(t *testing.T) {
+	if _, err := compilePattern("print ({{var)"); err == nil {
+		t.Error("ожидалась ошибка для незакрытого параметра")
+	}
+}
+
+// parseProgram разбирает программу; предупреждения считаются ошибкой
+func parseProgram(t *testing.T, src string) (*Program, error) {
+	t.Helper()
+	prog, err := newTestInterpreter(t).Parse(src)
+	if err == nil && len(prog.Warnings) > 0 {
+		return nil, prog.Warnings[0]
+	}
+	return prog, err
+}
+
+// shape описывает вложенность операторов: "For{If{Print} Print}"
+func shape(stmts []Stmt) string {
+	parts := make([]string, len(stmts))
+	for idx, stmt := range stmts {
+		name := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", stmt), "*main."), "Stmt")
+		switch s := stmt.(type) {
+		case *IfStmt:
+			name += "{" + shape(s.Body) + "}"
+			if s.Else != nil {
+				name += " Else{" + shape(s.Else) + "}"
+			}
+		case *ForStmt:
+			name += "{" + shape(s.Body) + "}"
+		case *WhileStmt:
+			name += "{" + shape(s.Body) + "}"
+		case *DoStmt:
+			name += "{" + shape(s.Body) + "}"
+		case *DefFuncStmt:
+			name += "{" + shape(s.Body) + "}"
+		case *SwitchStmt:
+			var cases []string
+			for _, c := range s.Cases {
+				cases = append(cases, "Case{"+shape(c.Body)+"}")
+			}
+			if s.Default != nil {
+				cases = append(cases, "Default{"+shape(s.Default.Body)+"}")
+			}
+			name += "{" + strings.Join(cases, " ") + "}"
+		}
+		parts[idx] = name
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParseNestedBlocks(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"for i from 1 to 3 {\n  if i == 2 {\n    print (i)\n  }\n  print (0)\n}\nprint (1)",
+			"For{If{Print} Print} Print"},
+		{"def f(n) {\n  while n > 0 {\n    do {\n      n = n - 1\n    }\n    while n > 5\n  }\n  return n\n}",
+			"DefFunc{While{Do{Assign}} Return}"},
+		{"switch x {\ncase 1:\n  if x {\n    print (x)\n  }\ncase 2:\ndefault:\n  print (0)\n}",
+			"Switch{Case{If{Print}} Case{} Default{Print}}"},
+		{"while true {\n  for i from 1 to 2 {\n    for j from 1 to 2 {\n    }\n  }\n}",
+			"While{For{For{}}}"},
+	}
+	for _, tt := range tests {
+		prog, err := parseProgram(t, tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if got := shape(prog.Stmts); got != tt.want {
+			t.Errorf("%q:\nполучено  %s\nожидалось %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseBlockErrors(t *testing.T) {
+	for _, src := range []string{
+		"if x {\n  print (x)",
+		"for i from 1 to 2 {\n  while true {\n  }",
+		"do {\n  print (1)\n}",
+		"switch x {\n  print (x)\n}",
+	} {
+		if _, err := parseProgram(t, src); err == nil {
+			t.Errorf("%q: ожидалась ошибка разбора", src)
+		}
+	}
+}
+
+func TestParseElseChain(t *testing.T) {
+	prog, err := parseProgram(t, "if a {\n  print (1)\n} else if b {\n  print (2)\n} else {\n  print (3)\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := shape(prog.Stmts), "If{Print} Else{If{Print} Else{Print}}"; got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+	if _, err := parseProgram(t, "print (1)\n} else {\n  print (2)\n}"); err == nil {
+		t.Error("else без if должен быть ошибкой")
+	}
+}
+
+func TestParseBreakOutsideLoop(t *testing.T) {
+	for _, src := range []string{
+		"break",
+		"continue",
+		"if true {\n  break\n}",
+		"for i from 1 to 2 {\n  def f() {\n    break\n  }\n}",
+	} {
+		if _, err := parseProgram(t, src); err == nil {
+			t.Errorf("%q: break/continue вне цикла должны быть ошибкой", src)
+		}
+	}
+	prog, err := parseProgram(t, "while true {\n  if true {\n    break\n  } else {\n    continue\n  }\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := shape(prog.Stmts), "While{If{Break} Else{Continue}}"; got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+}
+
+func TestUnknownCommandSuggestions(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // подстрока сообщения
+	}{
+		{"prnt (x)", "возможно, имелось в виду `print`?"},
+		{"solv (1 + 2)", "возможно, имелось в виду `solve`?"},
+		{"print x", "не соответствует шаблону команды print: `print ({{var}})`"},
+		{"completely_unrelated_words here", "\"completely_unrelated_words here\""},
+	}
+	for _, tt := range tests {
+		prog, err := newTestInterpreter(t).Parse(tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if len(prog.Warnings) != 1 {
+			t.Errorf("%q: предупреждений %d, ожидалось 1", tt.src, len(prog.Warnings))
+			continue
+		}
+		w := prog.Warnings[0]
+		if w.Kind != KindUnknownCommand || !strings.Contains(w.Message, tt.want) {
+			t.Errorf("%q: %v, ожидалось сообщение с %q", tt.src, w, tt.want)
+		}
+	}
+}
+
+func TestUnknownCommandSkipsBlock(t *testing.T) {
+	prog, err := newTestInterpreter(t).Parse("iff x {\n  print (1)\n}\nprint (2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := shape(prog.Stmts), "Print"; got != want || len(prog.Warnings) != 1 {
+		t.Errorf("получено %s и %d предупреждений, ожидалось %s и 1", got, len(prog.Warnings), want)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	interp := newTestInterpreter(t)
+	interp.SetStrict(true)
+	_, err := interp.Parse("print (1)\nprnt (2)\nprnt (3)")
+	if err == nil {
+		t.Fatal("в строгом режиме ожидалась ошибка")
+	}
+	ce := err.(*ClashError)
+	if ce.Kind != KindUnknownCommand || ce.Pos.Line != 2 {
+		t.Errorf("ошибка %v, ожидалась неизвестная команда в строке 2", ce)
+	}
+}
+
+func TestExpressionArgumentErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// Неразбираемый аргумент не превращается в текст
+		{"print (x +)", "(ожидалось выражение)"},
+		{"list_append (xs, 1 2)", "(лишняя лексема \"2\")"},
+	}
+	for _, tt := range tests {
+		prog, err := newTestInterpreter(t).Parse(tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if len(prog.Stmts) != 0 || len(prog.Warnings) != 1 || !strings.Contains(prog.Warnings[0].Message, tt.want) {
+			t.Errorf("%q: операторы %s, предупреждения %v; ожидалось предупреждение с %q", tt.src, shape(prog.Stmts), prog.Warnings, tt.want)
+		}
+	}
+}
+
+func TestParseAssignment(t *testing.T) {
+	prog, err := parseProgram(t, "x = 1 + 2\ny = upper(\"a\")\nsolve.out = z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := shape(prog.Stmts), "Assign Assign ResultOut"; got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+	for _, src := range []string{"x =", "x = 1 +"} {
+		if _, err := parseProgram(t, src); err == nil {
+			t.Errorf("%q: ожидалась ошибка разбора", src)
+		}
+	}
+}
+
+func TestParseCollectionLiterals(t *testing.T) {
+	prog, err := parseProgram(t, "xs = [1, [2, 3], {\"a\": 1}]\nxs[0] = 1\nm[\"a\"][1] = xs[2][\"a\"]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := shape(prog.Stmts), "Assign Assign Assign"; got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+	target := prog.Stmts[2].(*AssignStmt).Target
+	outer, ok := target.(*IndexExpr)
+	if !ok {
+		t.Fatalf("цель присваивания %T, ожидался *IndexExpr", target)
+	}
+	if _, ok := outer.Target.(*IndexExpr); !ok {
+		t.Errorf("m[\"a\"][1]: внутренняя цель %T, ожидался *IndexExpr", outer.Target)
+	}
+	for _, src := range []string{"xs = [1, 2", "xs = [1 2]", "d = {\"a\" 1}", "d = {\"a\": 1", "xs[0 = 1"} {
+		if _, err := parseProgram(t, src); err == nil {
+			t.Errorf("%q: ожидалась ошибка разбора", src)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import "sort"
+
+// Scope — область видимости переменных. Поиск имени идёт от внутренней области
+// к внешним; присваивание не поднимается выше границы кадра вызова.
+type Scope struct {
+	vars   map[string]Value
+	parent *Scope
+	frame  bool // область кадра: глобальная или тело функции
+}
+
+// NewScope создаёт вложенную область блока (например, для переменной цикла)
+func NewScope(parent *Scope) *Scope {
+	return &Scope{vars: make(map[string]Value), parent: parent}
+}
+
+// NewFrameScope создаёт область кадра вызова функции
+func NewFrameScope(parent *Scope) *Scope {
+	s := NewScope(parent)
+	s.frame = true
+	return s
+}
+
+// Lookup ищет переменную во всех доступных областях
+func (s *Scope) Lookup(name string) (Value, bool) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if val, ok := cur.vars[name]; ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// Define создаёт переменную в этой области
+func (s *Scope) Define(name string, val Value) {
+	s.vars[name] = val
+}
+
+// Assign изменяет ближайшую переменную с таким именем в пределах кадра,
+// а если её нет — создаёт её в области кадра
+func (s *Scope) Assign(name string, val Value) {
+	cur := s
+	for {
+		if _, ok := cur.vars[name]; ok || cur.frame || cur.parent == nil {
+			break
+		}
+		cur = cur.parent
+	}
+	cur.vars[name] = val
+}
+
+// Visible возвращает видимые переменные: внутренние области перекрывают внешние
+func (s *Scope) Visible() map[string]Value {
+	visible := make(map[string]Value)
+	for cur := s; cur != nil; cur = cur.parent {
+		for name, val := range cur.vars {
+			if _, shadowed := visible[name]; !shadowed {
+				visible[name] = val
+			}
+		}
+	}
+	return visible
+}
+
+// sortedNames возвращает имена переменных по алфавиту
+func sortedNames(vars map[string]Value) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CallFrame — запись о вызове функции в стеке вызовов
+type CallFrame struct {
+	Function *Function
+	Pos      Pos // место вызова
+}
+
+// withScope выполняет fn с указанной текущей областью видимости
+func (i *Interpreter) withScope(scope *Scope, fn func() error) error {
+	saved := i.scope
+	i.scope = scope
+	defer func() { i.scope = saved }()
+	return fn()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtinForms — конструкции, которые разбираются парсером напрямую, а не по commands.json
+var builtinForms = []string{"Function", "Memory load", "memory start"}
+
+// keyword возвращает ведущие слова шаблона команды ("print", "solve.input", "memory out")
+// и число их лексем; пустая строка — шаблон начинается не со слова (например, "} else {")
+func (e dispatchEntry) keyword() (string, int) {
+	end := 0
+	for end < len(e.Elems) {
+		el := e.Elems[end]
+		if el.Param != "" || (el.Literal.Kind != TokIdent && el.Literal.Text != ".") {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		return "", 0
+	}
+	first, last := e.Elems[0].Literal, e.Elems[end-1].Literal
+	return e.Cmd.Pattern[first.Offset : last.Offset+len(last.Text)], end
+}
+
+// lineHead возвращает текст первых n лексем строки, если все они — слова или точки
+func (p *Parser) lineHead(line []Token, n int) string {
+	if n > len(line) {
+		return ""
+	}
+	for _, tok := range line[:n] {
+		if tok.Kind != TokIdent && tok.Text != "." {
+			return ""
+		}
+	}
+	last := line[n-1]
+	return p.src[line[0].Offset : last.Offset+len(last.Text)]
+}
+
+// unknownCommand описывает нераспознанную строку и подсказывает ближайшую команду
+func (p *Parser) unknownCommand(line []Token) *ClashError {
+	last := line[len(line)-1]
+	text := p.src[line[0].Offset : last.Offset+len(last.Text)]
+	err := newError(KindUnknownCommand, "%q", text)
+	err.Pos = line[0].Pos
+
+	best, bestDist := "", -1
+	var bestEntry *dispatchEntry
+	consider := func(candidate string, words int, entry *dispatchEntry) {
+		head := p.lineHead(line, words)
+		if head == "" {
+			return
+		}
+		d := editDistance(strings.ToLower(head), strings.ToLower(candidate))
+		if bestDist == -1 || d < bestDist {
+			best, bestDist, bestEntry = candidate, d, entry
+		}
+	}
+	entries := p.table.Entries()
+	for idx := range entries {
+		if kw, words := entries[idx].keyword(); kw != "" {
+			consider(kw, words, &entries[idx])
+		}
+	}
+	for _, form := range builtinForms {
+		consider(form, len(strings.Fields(form)), nil)
+	}
+
+	switch {
+	case bestDist == 0 && bestEntry != nil:
+		err.Message = fmt.Sprintf("%q не соответствует шаблону команды %s: `%s`", text, best, bestEntry.Cmd.Pattern)
+		if p.mismatch != nil {
+			err.Message += fmt.Sprintf(" (%v)", p.mismatch)
+		}
+	case bestDist > 0 && bestDist <= maxSuggestDistance(best):
+		err.Message += fmt.Sprintf(", возможно, имелось в виду `%s`?", best)
+	}
+	return err
+}
+
+// maxSuggestDistance — насколько слово может отличаться от команды, чтобы её предложить
+func maxSuggestDistance(candidate string) int {
+	if n := utf8.RuneCountInString(candidate) / 3; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// editDistance — расстояние Левенштейна между строками (в символах)
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
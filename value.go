@@ -0,0 +1,362 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Type — тип значения ClashLang
+type Type int
+
+const (
+	TypeNil Type = iota
+	TypeInt
+	TypeBigInt
+	TypeFloat
+	TypeDecimal
+	TypeString
+	TypeBool
+	TypeList
+	TypeDict
+	TypeFunction
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeBigInt:
+		return "bigint"
+	case TypeFloat:
+		return "float"
+	case TypeDecimal:
+		return "decimal"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeList:
+		return "list"
+	case TypeDict:
+		return "dict"
+	case TypeFunction:
+		return "function"
+	}
+	return "nil"
+}
+
+// Value — значение переменной, результата команды или выражения.
+// String возвращает вид значения при выводе через print.
+type Value interface {
+	Type() Type
+	String() string
+}
+
+// Int — целое число; большие числа хранятся в BigInt
+type Int int64
+
+// Float — дробное число
+type Float float64
+
+// Str — текст
+type Str string
+
+// Bool — логическое значение
+type Bool bool
+
+// NilValue — отсутствие значения
+type NilValue struct{}
+
+// Nil — единственное значение типа nil
+var Nil Value = NilValue{}
+
+// List — список; изменяется на месте, все переменные с ним видят изменения
+type List struct {
+	Items []Value
+}
+
+// Dict — словарь с текстовыми ключами; ключи перебираются в порядке добавления
+type Dict struct {
+	keys []string
+	vals map[string]Value
+}
+
+// FuncValue — пользовательская функция как значение
+type FuncValue struct {
+	Fn *Function
+}
+
+func (Int) Type() Type        { return TypeInt }
+func (Float) Type() Type      { return TypeFloat }
+func (Str) Type() Type        { return TypeString }
+func (Bool) Type() Type       { return TypeBool }
+func (NilValue) Type() Type   { return TypeNil }
+func (*List) Type() Type      { return TypeList }
+func (*Dict) Type() Type      { return TypeDict }
+func (*FuncValue) Type() Type { return TypeFunction }
+
+func (n Int) String() string { return strconv.FormatInt(int64(n), 10) }
+
+func (f Float) String() string { return strconv.FormatFloat(float64(f), 'g', -1, 64) }
+
+func (s Str) String() string { return string(s) }
+
+func (b Bool) String() string { return strconv.FormatBool(bool(b)) }
+
+func (NilValue) String() string { return "nil" }
+
+func (l *List) String() string { return l.format(map[Value]bool{}) }
+
+func (d *Dict) String() string { return d.format(map[Value]bool{}) }
+
+// format выводит список; seen — списки и словари, которые выводятся сейчас:
+// список, содержащий сам себя, выводится как [...]
+func (l *List) format(seen map[Value]bool) string {
+	if seen[l] {
+		return "[...]"
+	}
+	seen[l] = true
+	defer delete(seen, l)
+	parts := make([]string, len(l.Items))
+	for idx, item := range l.Items {
+		parts[idx] = reprSeen(item, seen)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (d *Dict) format(seen map[Value]bool) string {
+	if seen[d] {
+		return "{...}"
+	}
+	seen[d] = true
+	defer delete(seen, d)
+	parts := make([]string, len(d.keys))
+	for idx, key := range d.keys {
+		parts[idx] = strconv.Quote(key) + ": " + reprSeen(d.vals[key], seen)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (f *FuncValue) String() string { return "<функция " + f.Fn.Name + ">" }
+
+// repr — вид значения внутри списка или словаря: текст берётся в кавычки
+func repr(v Value) string {
+	return reprSeen(v, map[Value]bool{})
+}
+
+func reprSeen(v Value, seen map[Value]bool) string {
+	switch n := v.(type) {
+	case Str:
+		return strconv.Quote(string(n))
+	case *List:
+		return n.format(seen)
+	case *Dict:
+		return n.format(seen)
+	}
+	return v.String()
+}
+
+// NewList создаёт список из элементов
+func NewList(items ...Value) *List {
+	return &List{Items: items}
+}
+
+// NewDict создаёт пустой словарь
+func NewDict() *Dict {
+	return &Dict{vals: make(map[string]Value)}
+}
+
+// Get возвращает значение по ключу
+func (d *Dict) Get(key string) (Value, bool) {
+	val, ok := d.vals[key]
+	return val, ok
+}
+
+// Set добавляет или заменяет значение; новый ключ встаёт в конец порядка перебора
+func (d *Dict) Set(key string, val Value) {
+	if _, ok := d.vals[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.vals[key] = val
+}
+
+// Delete удаляет ключ; false — ключа не было
+func (d *Dict) Delete(key string) bool {
+	if _, ok := d.vals[key]; !ok {
+		return false
+	}
+	delete(d.vals, key)
+	for idx, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:idx], d.keys[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys возвращает ключи в порядке добавления
+func (d *Dict) Keys() []string {
+	return append([]string(nil), d.keys...)
+}
+
+// Len — число элементов словаря
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
+
+// valuesEqual сравнивает значения на равенство: числа — по величине,
+// списки и словари — поэлементно
+func valuesEqual(left, right Value) bool {
+	return equalSeen(left, right, map[[2]Value]bool{})
+}
+
+// equalSeen — valuesEqual для вложенных значений; seen — пары списков и словарей,
+// которые сравниваются сейчас. Повторная встреча пары означает цикл: по нему
+// различий не найдено, остальное решат другие элементы.
+func equalSeen(left, right Value, seen map[[2]Value]bool) bool {
+	if isInteger(left) && isInteger(right) {
+		return compareInts(left, right) == 0
+	}
+	if isDecimalOperand(left, right) {
+		a, leftOk := toDecimal(left)
+		b, rightOk := toDecimal(right)
+		if leftOk && rightOk {
+			return compareDecimals(a, b) == 0
+		}
+	}
+	leftNum, leftIsNum := toFloat(left)
+	rightNum, rightIsNum := toFloat(right)
+	if leftIsNum || rightIsNum {
+		return leftIsNum && rightIsNum && leftNum == rightNum
+	}
+	switch left.(type) {
+	case *List, *Dict:
+		if left == right {
+			return true
+		}
+		pair := [2]Value{left, right}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+		defer delete(seen, pair)
+	}
+	switch l := left.(type) {
+	case *List:
+		r, ok := right.(*List)
+		if !ok || len(l.Items) != len(r.Items) {
+			return false
+		}
+		for idx := range l.Items {
+			if !equalSeen(l.Items[idx], r.Items[idx], seen) {
+				return false
+			}
+		}
+		return true
+	case *Dict:
+		r, ok := right.(*Dict)
+		if !ok || l.Len() != r.Len() {
+			return false
+		}
+		for _, key := range l.keys {
+			rv, ok := r.vals[key]
+			if !ok || !equalSeen(l.vals[key], rv, seen) {
+				return false
+			}
+		}
+		return true
+	case *FuncValue:
+		r, ok := right.(*FuncValue)
+		return ok && l.Fn == r.Fn
+	}
+	return left == right
+}
+
+// truthy определяет логическое значение условия: ложны false, 0, пустой текст,
+// пустой список или словарь и nil
+func truthy(v Value) bool {
+	switch n := v.(type) {
+	case NilValue:
+		return false
+	case Bool:
+		return bool(n)
+	case Int:
+		return n != 0
+	case BigInt:
+		return n.n.Sign() != 0
+	case Float:
+		return n != 0
+	case Decimal:
+		return n.unscaled.Sign() != 0
+	case Str:
+		return n != ""
+	case *List:
+		return len(n.Items) > 0
+	case *Dict:
+		return n.Len() > 0
+	}
+	return true
+}
+
+// toFloat приводит число к float64
+func toFloat(v Value) (float64, bool) {
+	switch n := v.(type) {
+	case Int:
+		return float64(n), true
+	case BigInt:
+		return bigToFloat(n), true
+	case Float:
+		return float64(n), true
+	case Decimal:
+		return decimalToFloat(n), true
+	}
+	return 0, false
+}
+
+// typeName возвращает имя типа значения для сообщений об ошибках
+func typeName(v Value) string {
+	if v == nil {
+		return "nil"
+	}
+	return v.Type().String()
+}
+
+// expectInt требует целое число; what — что это за значение, для сообщения об ошибке
+func expectInt(v Value, what string) (int, error) {
+	n, ok := v.(Int)
+	if _, big := v.(BigInt); big {
+		return 0, newError(KindValue, "%s: число %s слишком велико", what, v)
+	}
+	if !ok {
+		return 0, newError(KindType, "%s: ожидается int, получено значение типа %s", what, typeName(v))
+	}
+	return int(n), nil
+}
+
+// expectString требует текст
+func expectString(v Value, what string) (string, error) {
+	s, ok := v.(Str)
+	if !ok {
+		return "", newError(KindType, "%s: ожидается string, получено значение типа %s", what, typeName(v))
+	}
+	return string(s), nil
+}
+
+// expectList требует список
+func expectList(v Value, what string) (*List, error) {
+	l, ok := v.(*List)
+	if !ok {
+		return nil, newError(KindType, "%s: ожидается list, получено значение типа %s", what, typeName(v))
+	}
+	return l, nil
+}
+
+// expectDict требует словарь
+func expectDict(v Value, what string) (*Dict, error) {
+	d, ok := v.(*Dict)
+	if !ok {
+		return nil, newError(KindType, "%s: ожидается dict, получено значение типа %s", what, typeName(v))
+	}
+	return d, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+func TestValueString(t *testing.T) {
+	dict := NewDict()
+	dict.Set("b", Int(1))
+	dict.Set("a", NewList(Str("x"), Nil))
+	tests := []struct {
+		val  Value
+		want string
+	}{
+		{Int(-3), "-3"},
+		{Float(2.5), "2.5"},
+		{Str("текст"), "текст"},
+		{Bool(true), "true"},
+		{Nil, "nil"},
+		{NewList(Int(1), Float(2.5), Str("hi")), `[1, 2.5, "hi"]`},
+		{dict, `{"b": 1, "a": ["x", nil]}`},
+		{NewList(), "[]"},
+		{NewDict(), "{}"},
+	}
+	for _, tt := range tests {
+		if got := tt.val.String(); got != tt.want {
+			t.Errorf("%#v: %q, ожидалось %q", tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		want        bool
+	}{
+		{Int(2), Float(2), true},
+		{Int(2), Str("2"), false},
+		{Str("a"), Str("a"), true},
+		{Nil, Nil, true},
+		{Nil, Bool(false), false},
+		{NewList(Int(1), NewList(Str("a"))), NewList(Float(1), NewList(Str("a"))), true},
+		{NewList(Int(1)), NewList(Int(1), Int(2)), false},
+		{NewList(), NewDict(), false},
+	}
+	for _, tt := range tests {
+		if got := valuesEqual(tt.left, tt.right); got != tt.want {
+			t.Errorf("valuesEqual(%v, %v) = %v, ожидалось %v", tt.left, tt.right, got, tt.want)
+		}
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	for _, v := range []Value{Nil, Bool(false), Int(0), Float(0), Str(""), NewList(), NewDict()} {
+		if truthy(v) {
+			t.Errorf("%v (%s) должно быть ложным", v, v.Type())
+		}
+	}
+	for _, v := range []Value{Bool(true), Int(-1), Float(0.5), Str("0"), NewList(Nil)} {
+		if !truthy(v) {
+			t.Errorf("%v (%s) должно быть истинным", v, v.Type())
+		}
+	}
+}
+
+func TestSelfReferencingContainers(t *testing.T) {
+	xs := NewList(Int(1))
+	xs.Items = append(xs.Items, xs)
+	if got, want := xs.String(), "[1, [...]]"; got != want {
+		t.Errorf("список со ссылкой на себя: %q, ожидалось %q", got, want)
+	}
+	d := NewDict()
+	d.Set("self", d)
+	if got, want := d.String(), `{"self": {...}}`; got != want {
+		t.Errorf("словарь со ссылкой на себя: %q, ожидалось %q", got, want)
+	}
+	if !valuesEqual(d, d) || !valuesEqual(xs, xs) {
+		t.Error("значение должно быть равно самому себе")
+	}
+	ys := NewList(Int(1))
+	ys.Items = append(ys.Items, ys)
+	if !valuesEqual(xs, ys) {
+		t.Error("одинаковые циклические списки должны быть равны")
+	}
+	zs := NewList(Int(2))
+	zs.Items = append(zs.Items, zs)
+	if valuesEqual(xs, zs) {
+		t.Error("разные циклические списки не должны быть равны")
+	}
+	// Один и тот же список дважды — не цикл
+	if got, want := NewList(xs, xs).String(), "[[1, [...]], [1, [...]]]"; got != want {
+		t.Errorf("%q, ожидалось %q", got, want)
+	}
+}